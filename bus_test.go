@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+// TestBus tests that subscribers to the event bus get only the events they asked for,
+// and that a subscriber that never reads drops events instead of holding up the others.
+func TestBus(t *testing.T) {
+	p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 100, Threads: 8}
+	expectedAlive := readAliveCells("check/images/64x64x100.pgm", p.ImageWidth, p.ImageHeight)
+	events := make(chan gol.Event)
+	bus := gol.NewBus()
+	final := bus.Subscribe(gol.FinalTurnCompleteEvent, 1, gol.Block)
+	turns := bus.Subscribe(gol.TurnCompleteEvent, 10, gol.DropOldest)
+	stalled := bus.Subscribe(gol.CellFlippedEvent, 1, gol.DropNewest)
+	go gol.Run(p, events, nil)
+	go bus.Run(events)
+
+	turnsSeen := make(chan int)
+	go func() {
+		count := 0
+		for event := range turns.Events {
+			if _, ok := event.(gol.TurnComplete); !ok {
+				t.Errorf("TurnComplete subscriber was sent %T", event)
+			}
+			count++
+		}
+		turnsSeen <- count
+	}()
+
+	var finalEvents int
+	for event := range final.Events {
+		e, ok := event.(gol.FinalTurnComplete)
+		if !ok {
+			t.Fatalf("FinalTurnComplete subscriber was sent %T", event)
+		}
+		finalEvents++
+		assertEqualBoard(t, e.Alive, expectedAlive, p)
+	}
+	if finalEvents != 1 {
+		t.Errorf("expected 1 FinalTurnComplete event, got %v", finalEvents)
+	}
+	if count := <-turnsSeen; count+int(turns.Dropped()) != p.Turns {
+		t.Errorf("expected %v TurnComplete events to be received or dropped, got %v", p.Turns, count+int(turns.Dropped()))
+	}
+	if stalled.Dropped() == 0 {
+		t.Error("expected the stalled subscriber to drop CellFlipped events")
+	}
+}
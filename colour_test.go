@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// readShades reads the grey level of every cell that isn't black from a PGM image
+func readShades(path string, width, height int) map[util.Cell]uint8 {
+	data, err := ioutil.ReadFile(path)
+	util.Check(err)
+	fields := strings.Fields(string(data))
+	if fields[0] != "P5" || fields[1] != fmt.Sprint(width) || fields[2] != fmt.Sprint(height) {
+		panic("not a " + fmt.Sprint(width) + "x" + fmt.Sprint(height) + " pgm file")
+	}
+	image := data[len(data)-width*height:]
+	shades := make(map[util.Cell]uint8)
+	for i, shade := range image {
+		if shade != 0 {
+			shades[util.Cell{X: i % width, Y: i / width}] = shade
+		}
+	}
+	return shades
+}
+
+// advanceColours works out a turn of a Life-like rule where alive cells have colours, with born cells taking the
+// colour most of the alive cells around them have, or the one colour none of them have if they are tied
+func advanceColours(colours map[util.Cell]int, born, survive string, n, width, height int) map[util.Cell]int {
+	next := make(map[util.Cell]int)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			parents := make([]int, n)
+			count := 0
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					if colour, ok := colours[util.Cell{X: (x + dx + width) % width, Y: (y + dy + height) % height}]; ok && (dx != 0 || dy != 0) {
+						parents[colour]++
+						count++
+					}
+				}
+			}
+			cell := util.Cell{X: x, Y: y}
+			if colour, ok := colours[cell]; ok {
+				if strings.Contains(survive, fmt.Sprint(count)) {
+					next[cell] = colour
+				}
+				continue
+			}
+			if !strings.Contains(born, fmt.Sprint(count)) {
+				continue
+			}
+			best, missing := 0, []int{}
+			for colour := range parents {
+				if parents[colour] > parents[best] {
+					best = colour
+				}
+				if parents[colour] == 0 {
+					missing = append(missing, colour)
+				}
+			}
+			tied := 0
+			for colour := range parents {
+				if parents[colour] == parents[best] {
+					tied++
+				}
+			}
+			if tied > 1 && len(missing) == 1 {
+				best = missing[0]
+			}
+			next[cell] = best
+		}
+	}
+	return next
+}
+
+// TestColours tests Immigration and QuadLife from a soup of every colour against colours worked out here, from the
+// events and the output image, with different numbers of workers
+func TestColours(t *testing.T) {
+	tests := []struct {
+		rule, expectedRulestring string
+		born, survive            string
+		colours                  int
+	}{
+		{"Immigration", "Immigration", "3", "23", 2},
+		{"B3/S23QuadLife", "QuadLife", "3", "23", 4},
+		{"b36/s23quadlife", "B36/S23QuadLife", "36", "23", 4},
+	}
+	p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 40}
+	random := rand.New(rand.NewSource(1))
+	for _, test := range tests {
+		rule, err := gol.ParseRule(test.rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rule.String() != test.expectedRulestring || rule.Colours != test.colours {
+			t.Errorf("%v was read as %v with %d colours, not %v with %d", test.rule, rule, rule.Colours, test.expectedRulestring, test.colours)
+		}
+		p.Rule = test.rule
+		soup := gol.Pattern{Name: "soup", Width: p.ImageWidth, Height: p.ImageHeight}
+		colours := make(map[util.Cell]int)
+		for y := 0; y < p.ImageHeight; y++ {
+			for x := 0; x < p.ImageWidth; x++ {
+				if random.Intn(3) == 0 {
+					colour := random.Intn(test.colours)
+					soup.Cells = append(soup.Cells, util.Cell{X: x, Y: y})
+					soup.States = append(soup.States, colour+1)
+					colours[util.Cell{X: x, Y: y}] = colour
+				}
+			}
+		}
+		for turn := 0; turn < p.Turns; turn++ {
+			colours = advanceColours(colours, test.born, test.survive, test.colours, p.ImageWidth, p.ImageHeight)
+		}
+		expected := make(map[util.Cell]uint8)
+		for cell, colour := range colours {
+			expected[cell] = uint8(255 - colour*256/test.colours)
+		}
+
+		for _, threads := range []int{1, 3, 8} {
+			p.Threads = threads
+			t.Run(fmt.Sprintf("%v-%d", test.rule, threads), func(t *testing.T) {
+				commands := make(chan gol.Command, 1)
+				commands <- gol.StampPattern{Pattern: soup}
+				events := make(chan gol.Event)
+				go gol.RunWithCommands(p, events, nil, commands)
+				shades := make(map[util.Cell]uint8)
+				for event := range events {
+					switch e := event.(type) {
+					case gol.CellFlipped, gol.CellsFlipped:
+						t.Fatalf("%T can't say what colour a cell is", e)
+					case gol.CellStateChanged:
+						shades[e.Cell] = e.State
+						if e.State == 0 {
+							delete(shades, e.Cell)
+						}
+					}
+				}
+				output := readShades(fmt.Sprintf("out/64x64x%d.pgm", p.Turns), p.ImageWidth, p.ImageHeight)
+				for name, given := range map[string]map[util.Cell]uint8{"events": shades, "output": output} {
+					if len(given) != len(expected) {
+						t.Errorf("%v have %d alive cells, not %d", name, len(given), len(expected))
+					}
+					for cell, shade := range expected {
+						if given[cell] != shade {
+							t.Errorf("%v have %v as %d, not %d", name, cell, given[cell], shade)
+							break
+						}
+					}
+				}
+			})
+		}
+	}
+
+	t.Run("AliveCellsCount", func(t *testing.T) {
+		counted := p
+		counted.Rule, counted.Turns, counted.ImageWidth, counted.ImageHeight = "QuadLife", 100000000, 512, 512
+		events := make(chan gol.Event)
+		keyPresses := make(chan rune, 1)
+		go gol.Run(counted, events, keyPresses)
+		quit := false
+		for event := range events {
+			if e, ok := event.(gol.AliveCellsCount); ok && !quit {
+				total := 0
+				for _, count := range e.ColourCounts {
+					total += count
+				}
+				if len(e.ColourCounts) != 4 || total != e.CellsCount {
+					t.Errorf("%d alive cells were counted by colour as %v", e.CellsCount, e.ColourCounts)
+				}
+				keyPresses <- 'q'
+				quit = true
+			}
+		}
+	})
+
+	for _, rulestring := range []string{"B2/S/C3Immigration", "B2/S34HQuadLife", "R5,C0,M1,S34..58,B34..45,NMImmigration", "ImmigrationQuadLife"} {
+		if _, err := gol.ParseRule(rulestring); err == nil {
+			t.Errorf("%v should not be a valid rule with colours", rulestring)
+		}
+	}
+}
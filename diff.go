@@ -0,0 +1,98 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"uk.ac.bris.cs/gameoflife/diff"
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// runDiff is the diff command, started with 'go run . diff'. It compares two board images, a board image against a
+// run of -turns turns on an image of the same size, or with no images, two runs that differ by the flags ending in 2,
+// reporting the first turn after which they stop agreeing. It exits with 1 if the boards differ, like diff.
+func runDiff(args []string) {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: go run . diff [flags] [first.pgm] [second.pgm]")
+		flags.PrintDefaults()
+	}
+	var p gol.Params
+	flags.IntVar(&p.Threads, "t", 8, "Specify the number of worker threads of the run. Defaults to 8.")
+	flags.IntVar(&p.ImageWidth, "w", 512, "Specify the width of the image for two runs. Defaults to 512, or the width of the board image.")
+	flags.IntVar(&p.ImageHeight, "h", 512, "Specify the height of the image for two runs. Defaults to 512, or the height of the board image.")
+	flags.IntVar(&p.Turns, "turns", 0, "Specify the number of turns of the run to compare against. Defaults to 0.")
+	flags.BoolVar(&p.Unbounded, "unbounded", false, "Lets the board of the run grow forever instead of wrapping around the edges.")
+	flags.StringVar(&p.Rule, "rule", "B3/S23", "Specify the rule of the run. Defaults to Conway's Game of Life.")
+	flags.Float64Var(&p.Noise, "noise", 0, "Specify the noise of the run. Defaults to 0.")
+	flags.Int64Var(&p.Seed, "seed", 0, "Specify the seed for -noise. Defaults to 0.")
+	threads2 := flags.Int("t2", 0, "Specify the number of worker threads of the second run. Defaults to -t.")
+	rule2 := flags.String("rule2", "", "Specify the rule of the second run. Defaults to -rule.")
+	noise2 := flags.Float64("noise2", 0, "Specify the noise of the second run. Defaults to -noise.")
+	seed2 := flags.Int64("seed2", 0, "Specify the seed of the second run. Defaults to -seed.")
+	imagePath := flags.String("image", "", "Saves the differences as a PNG at this path, with added cells green, removed cells red and changed cells yellow.")
+	list := flags.Bool("list", true, "Lists every added (+), removed (-) and changed (~) cell.")
+	util.Check(flags.Parse(args))
+
+	var a, b diff.Board
+	switch flags.NArg() {
+	case 2:
+		var err error
+		a, err = diff.ReadBoard(flags.Arg(0))
+		util.Check(err)
+		b, err = diff.ReadBoard(flags.Arg(1))
+		util.Check(err)
+	case 1:
+		var err error
+		a, err = diff.ReadBoard(flags.Arg(0)) // before the run, which could output over it
+		util.Check(err)
+		p.ImageWidth, p.ImageHeight = a.Width, a.Height
+		b = diff.RunBoard(p)
+	case 0:
+		second := p
+		flags.Visit(func(f *flag.Flag) { // only the flags that were given, so e.g. -seed2 0 isn't taken as -seed
+			switch f.Name {
+			case "t2":
+				second.Threads = *threads2
+			case "rule2":
+				second.Rule = *rule2
+			case "noise2":
+				second.Noise = *noise2
+			case "seed2":
+				second.Seed = *seed2
+			}
+		})
+		divergence, diverged, err := diff.FirstDivergence(p, second)
+		util.Check(err)
+		if !diverged {
+			fmt.Printf("The runs agree for all %d turns\n", p.Turns)
+			return
+		}
+		fmt.Printf("The runs diverge after turn %d\n", divergence.Turn)
+		a, b = divergence.First, divergence.Second
+	default:
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	differences := diff.Compare(a, b)
+	fmt.Println(differences)
+	if *list {
+		for _, cells := range []struct {
+			mark  string
+			cells []util.Cell
+		}{{"+", differences.Added}, {"-", differences.Removed}, {"~", differences.Changed}} {
+			for _, cell := range cells.cells {
+				fmt.Printf("%v %d,%d\n", cells.mark, cell.X, cell.Y)
+			}
+		}
+	}
+	if *imagePath != "" {
+		util.Check(diff.WriteImage(*imagePath, a, b))
+	}
+	if !differences.Empty() {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,330 @@
+package diff
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// Board holds the cells of a board that aren't dead, by the grey level they are stored as, and the size of the image
+// the board is drawn in. Cells of an unbounded run can be outside the image.
+type Board struct {
+	Width  int
+	Height int
+	Cells  map[util.Cell]uint8
+}
+
+// Diff is how a second board differs from a first
+type Diff struct {
+	Added   []util.Cell // cells that are only alive in the second board
+	Removed []util.Cell // cells that are only alive in the first board
+	Changed []util.Cell // cells that aren't dead in either board but have different grey levels, e.g. other colours
+	Low     util.Cell   // the top left of the smallest rectangle holding every difference
+	High    util.Cell   // the bottom right of that rectangle, which isn't in it
+}
+
+// Divergence is where two runs stop agreeing
+type Divergence struct {
+	Turn   int   // the first turn after which the boards of the two runs differ
+	Diff   Diff  // how the board of the second run differs from the first after that turn
+	First  Board // the board of the first run after that turn
+	Second Board // the board of the second run after that turn
+}
+
+// colours of the cells in a diff image, by the index WriteImage gives them
+var colours = color.Palette{
+	color.Black, // dead in both
+	color.RGBA{R: 0x60, G: 0x60, B: 0x60, A: 0xFF}, // the same in both
+	color.RGBA{R: 0xFF, G: 0x30, B: 0x30, A: 0xFF}, // removed
+	color.RGBA{R: 0x30, G: 0xE0, B: 0x30, A: 0xFF}, // added
+	color.RGBA{R: 0xFF, G: 0xE0, B: 0x30, A: 0xFF}, // changed
+}
+
+// FromCells creates a board of the given size from a list of alive cells
+func FromCells(cells []util.Cell, width int, height int) Board {
+	board := Board{Width: width, Height: height, Cells: make(map[util.Cell]uint8, len(cells))}
+	for _, cell := range cells {
+		board.Cells[cell] = 255
+	}
+	return board
+}
+
+// ReadBoard reads a board from a PGM image such as the ones the game outputs
+func ReadBoard(path string) (Board, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Board{}, err
+	}
+	var header []string
+	i := 0
+	for len(header) < 4 && i < len(data) {
+		switch {
+		case data[i] == '#': // a comment, up to the end of the line
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+		case data[i] == ' ' || data[i] == '\t' || data[i] == '\r' || data[i] == '\n':
+			i++
+		default:
+			start := i
+			for i < len(data) && !strings.ContainsRune(" \t\r\n", rune(data[i])) {
+				i++
+			}
+			header = append(header, string(data[start:i]))
+		}
+	}
+	if len(header) < 4 || header[0] != "P5" {
+		return Board{}, fmt.Errorf("%v is not a binary PGM image", path)
+	}
+	width, widthErr := strconv.Atoi(header[1])
+	height, heightErr := strconv.Atoi(header[2])
+	if widthErr != nil || heightErr != nil || header[3] != "255" {
+		return Board{}, fmt.Errorf("%v should have a width, a height and a maxval of 255", path)
+	}
+	pixels := data[minInt(i+1, len(data)):] // after the one whitespace character that ends the header
+	if len(pixels) < width*height {
+		return Board{}, fmt.Errorf("%v should have %dx%d pixels, but only has %d", path, width, height, len(pixels))
+	}
+	board := Board{Width: width, Height: height, Cells: make(map[util.Cell]uint8)}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if value := pixels[y*width+x]; value != 0 {
+				board.Cells[util.Cell{X: x, Y: y}] = value
+			}
+		}
+	}
+	return board, nil
+}
+
+// follow builds up a board from the events of a run, calling turnComplete with every cell that changed since the last
+// TurnComplete. It quits the run once turnComplete returns false, but still reads every event so the run can finish.
+func follow(events <-chan gol.Event, keyPresses chan<- rune, board Board, turnComplete func(turn int, changed []util.Cell) bool) {
+	var changed []util.Cell
+	set := func(cell util.Cell, value uint8) {
+		if value == 0 {
+			delete(board.Cells, cell)
+		} else {
+			board.Cells[cell] = value
+		}
+		changed = append(changed, cell)
+	}
+	flip := func(cell util.Cell) {
+		set(cell, 255-board.Cells[cell]) // between dead and alive
+	}
+	running := true
+	for event := range events {
+		switch e := event.(type) {
+		case gol.CellFlipped:
+			flip(e.Cell)
+		case gol.CellsFlipped:
+			for _, cell := range e.Cells {
+				flip(cell)
+			}
+		case gol.CellStateChanged:
+			set(e.Cell, e.State)
+		case gol.TurnComplete:
+			if running && !turnComplete(e.CompletedTurns, changed) {
+				keyPresses <- 'q'
+				running = false
+			}
+			changed = changed[:0]
+		}
+	}
+}
+
+// RunBoard runs the game for p.Turns turns and returns the board it finishes with. Like any run, it reads its image
+// from the images directory and outputs the final board to the out directory.
+func RunBoard(p gol.Params) Board {
+	events := make(chan gol.Event, 1000)
+	keyPresses := make(chan rune, 1)
+	go gol.Run(p, events, keyPresses)
+	board := Board{Width: p.ImageWidth, Height: p.ImageHeight, Cells: make(map[util.Cell]uint8)}
+	follow(events, keyPresses, board, func(int, []util.Cell) bool { return true })
+	return board
+}
+
+// FirstDivergence runs two games on the same board, one after the other, and finds the first turn after which their
+// boards differ, e.g. because they have different rules, noise seeds or numbers of threads. It returns false if the
+// boards agree for every turn both runs have. The changes of every turn of the first run are kept, which are usually
+// much smaller than the boards.
+func FirstDivergence(a gol.Params, b gol.Params) (Divergence, bool, error) {
+	if a.ImageWidth != b.ImageWidth || a.ImageHeight != b.ImageHeight || a.Depth != b.Depth {
+		return Divergence{}, false, errors.New("runs on boards of different sizes can't be compared turn by turn")
+	}
+	type change struct {
+		cell  util.Cell
+		value uint8
+	}
+	boardA := Board{Width: a.ImageWidth, Height: a.ImageHeight, Cells: make(map[util.Cell]uint8)}
+	var turns [][]change // the cells each turn of the first run changed, and what to
+	events := make(chan gol.Event, 1000)
+	keyPresses := make(chan rune, 1)
+	go gol.Run(a, events, keyPresses)
+	follow(events, keyPresses, boardA, func(turn int, changed []util.Cell) bool {
+		changes := make([]change, len(changed))
+		for i, cell := range changed {
+			changes[i] = change{cell, boardA.Cells[cell]}
+		}
+		turns = append(turns, changes)
+		return true
+	})
+
+	boardA.Cells = make(map[util.Cell]uint8) // replayed alongside the second run
+	boardB := Board{Width: b.ImageWidth, Height: b.ImageHeight, Cells: make(map[util.Cell]uint8)}
+	differs := make(map[util.Cell]bool)
+	update := func(cell util.Cell) {
+		if boardA.Cells[cell] != boardB.Cells[cell] {
+			differs[cell] = true
+		} else {
+			delete(differs, cell)
+		}
+	}
+	var divergence Divergence
+	diverged := false
+	events = make(chan gol.Event, 1000)
+	go gol.Run(b, events, keyPresses)
+	follow(events, keyPresses, boardB, func(turn int, changed []util.Cell) bool {
+		if len(turns) == 0 { // the first run had no more turns
+			return false
+		}
+		for _, c := range turns[0] {
+			if c.value == 0 {
+				delete(boardA.Cells, c.cell)
+			} else {
+				boardA.Cells[c.cell] = c.value
+			}
+			update(c.cell)
+		}
+		turns = turns[1:]
+		for _, cell := range changed {
+			update(cell)
+		}
+		if len(differs) > 0 {
+			divergence, diverged = Divergence{Turn: turn, Diff: Compare(boardA, boardB), First: boardA.copy(), Second: boardB.copy()}, true
+			return false // the rest of the events of the run still change boardB
+		}
+		return true
+	})
+	return divergence, diverged, nil
+}
+
+// copy returns a board with its own map of cells
+func (board Board) copy() Board {
+	cells := make(map[util.Cell]uint8, len(board.Cells))
+	for cell, value := range board.Cells {
+		cells[cell] = value
+	}
+	return Board{Width: board.Width, Height: board.Height, Cells: cells}
+}
+
+// Compare finds the cells that differ between two boards, in reading order
+func Compare(a Board, b Board) Diff {
+	var diff Diff
+	cells := make(map[util.Cell]bool, len(a.Cells)+len(b.Cells))
+	for cell := range a.Cells {
+		cells[cell] = true
+	}
+	for cell := range b.Cells {
+		cells[cell] = true
+	}
+	var differences []util.Cell
+	for cell := range cells {
+		if a.Cells[cell] != b.Cells[cell] {
+			differences = append(differences, cell)
+		}
+	}
+	sort.Slice(differences, func(i, j int) bool {
+		return differences[i].Y < differences[j].Y || differences[i].Y == differences[j].Y && differences[i].X < differences[j].X
+	})
+	for i, cell := range differences {
+		switch {
+		case a.Cells[cell] == 0:
+			diff.Added = append(diff.Added, cell)
+		case b.Cells[cell] == 0:
+			diff.Removed = append(diff.Removed, cell)
+		default:
+			diff.Changed = append(diff.Changed, cell)
+		}
+		if i == 0 {
+			diff.Low, diff.High = cell, util.Cell{X: cell.X + 1, Y: cell.Y + 1}
+		}
+		diff.Low = util.Cell{X: minInt(diff.Low.X, cell.X), Y: minInt(diff.Low.Y, cell.Y)}
+		diff.High = util.Cell{X: maxInt(diff.High.X, cell.X+1), Y: maxInt(diff.High.Y, cell.Y+1)}
+	}
+	return diff
+}
+
+// Empty checks if the two boards are the same
+func (diff Diff) Empty() bool {
+	return len(diff.Added) == 0 && len(diff.Removed) == 0 && len(diff.Changed) == 0
+}
+
+// String summarises a diff, e.g. "3 added, 1 removed, 0 changed, within (4, 2) to (9, 6)"
+func (diff Diff) String() string {
+	if diff.Empty() {
+		return "no differences"
+	}
+	return fmt.Sprintf("%d added, %d removed, %d changed, within (%d, %d) to (%d, %d)", len(diff.Added), len(diff.Removed),
+		len(diff.Changed), diff.Low.X, diff.Low.Y, diff.High.X-1, diff.High.Y-1)
+}
+
+// WriteImage saves a PNG of the second board coloured by how it differs from the first: added cells are green,
+// removed cells red, changed cells yellow, and cells that are the same grey. It is the size of the larger board, so
+// cells of an unbounded run outside that are left out.
+func WriteImage(path string, a Board, b Board) error {
+	width, height := maxInt(a.Width, b.Width), maxInt(a.Height, b.Height)
+	frame := image.NewPaletted(image.Rect(0, 0, width, height), colours)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			cell := util.Cell{X: x, Y: y}
+			before, after := a.Cells[cell], b.Cells[cell]
+			switch {
+			case before == 0 && after == 0:
+			case before == after:
+				frame.Pix[y*frame.Stride+x] = 1
+			case after == 0:
+				frame.Pix[y*frame.Stride+x] = 2
+			case before == 0:
+				frame.Pix[y*frame.Stride+x] = 3
+			default:
+				frame.Pix[y*frame.Stride+x] = 4
+			}
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return png.Encode(file, frame)
+}
+
+// minInt returns the smaller of two ints
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// maxInt returns the larger of two ints
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
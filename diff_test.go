@@ -0,0 +1,123 @@
+package main
+
+import (
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/diff"
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestDiff tests comparing board images and runs, finding where runs diverge against Life worked out here, and the
+// colours of diff images
+func TestDiff(t *testing.T) {
+	const width, height = 64, 64
+	before, err := diff.ReadBoard("images/64x64.pgm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := diff.ReadBoard("check/images/64x64x1.pgm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	alive := make(map[util.Cell]int)
+	for _, cell := range readAliveCells("images/64x64.pgm", width, height) {
+		alive[cell] = 0
+	}
+	turned := advanceColours(alive, "3", "23", 1, width, height)
+	differences := diff.Compare(before, after)
+	for _, cell := range differences.Added {
+		if _, ok := alive[cell]; ok {
+			t.Errorf("%v was added but was already alive", cell)
+		}
+	}
+	for _, cell := range differences.Removed {
+		if _, ok := turned[cell]; ok {
+			t.Errorf("%v was removed but is still alive", cell)
+		}
+	}
+	added, removed := 0, 0
+	for cell := range turned {
+		if _, ok := alive[cell]; !ok {
+			added++
+		}
+	}
+	for cell := range alive {
+		if _, ok := turned[cell]; !ok {
+			removed++
+		}
+	}
+	if len(differences.Added) != added || len(differences.Removed) != removed || len(differences.Changed) != 0 {
+		t.Errorf("expected %d added and %d removed, not %v", added, removed, differences)
+	}
+	for _, cell := range append(differences.Added, differences.Removed...) {
+		if cell.X < differences.Low.X || cell.Y < differences.Low.Y || cell.X >= differences.High.X || cell.Y >= differences.High.Y {
+			t.Errorf("%v is outside the differences from %v to %v", cell, differences.Low, differences.High)
+		}
+	}
+
+	p := gol.Params{ImageWidth: width, ImageHeight: height, Turns: 1, Threads: 4}
+	if run := diff.Compare(after, diff.RunBoard(p)); !run.Empty() {
+		t.Errorf("a run of 1 turn should match check/images/64x64x1.pgm, not have %v", run)
+	}
+
+	p.Turns = 50
+	if divergence, diverged, err := diff.FirstDivergence(p, gol.Params{ImageWidth: width, ImageHeight: height, Turns: 50, Threads: 7}); err != nil || diverged {
+		t.Errorf("runs with different numbers of threads should agree, not diverge after turn %d with %v", divergence.Turn, divergence.Diff)
+	}
+	highLife := p
+	highLife.Rule = "B36/S23"
+	expectedTurn := 0
+	life, high := alive, alive
+	for turn := 1; turn <= p.Turns && expectedTurn == 0; turn++ {
+		life = advanceColours(life, "3", "23", 1, width, height)
+		high = advanceColours(high, "36", "23", 1, width, height)
+		if !diff.Compare(colouredBoard(life), colouredBoard(high)).Empty() {
+			expectedTurn = turn
+		}
+	}
+	divergence, diverged, err := diff.FirstDivergence(p, highLife)
+	if err != nil || !diverged || divergence.Turn != expectedTurn {
+		t.Fatalf("Life and HighLife should diverge after turn %d, not %d", expectedTurn, divergence.Turn)
+	}
+	if expected := diff.Compare(colouredBoard(life), colouredBoard(high)); expected.String() != divergence.Diff.String() {
+		t.Errorf("Life and HighLife should differ by %v, not %v", expected, divergence.Diff)
+	}
+
+	dir, err := ioutil.TempDir("", "diff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "diff.png")
+	if err := diff.WriteImage(path, divergence.First, divergence.Second); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	image, err := png.Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cell := range divergence.Diff.Added { // HighLife gives birth with 6 alive neighbours as well
+		if r, g, _, _ := image.At(cell.X, cell.Y).RGBA(); g <= r {
+			t.Errorf("added cell %v should be green", cell)
+		}
+	}
+}
+
+// colouredBoard turns cells with colours back into a board, for comparing
+func colouredBoard(cells map[util.Cell]int) diff.Board {
+	board := diff.Board{Width: 64, Height: 64, Cells: make(map[util.Cell]uint8)}
+	for cell := range cells {
+		board.Cells[cell] = 255
+	}
+	return board
+}
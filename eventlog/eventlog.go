@@ -0,0 +1,236 @@
+package eventlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// paramsType is the type of the first record in every log, which holds the Params of the run
+const paramsType = "Params"
+
+// record is one line of the log
+type record struct {
+	Type    string          `json:"type"`
+	Turn    int             `json:"turn"`
+	Millis  int64           `json:"ms"` // time since the start of the run, so a replay can keep the same pace
+	Payload json.RawMessage `json:"payload"`
+}
+
+// typeName returns the name written in the log for an event
+func typeName(event gol.Event) (string, error) {
+	switch event.(type) {
+	case gol.AliveCellsCount:
+		return "AliveCellsCount", nil
+	case gol.ImageOutputComplete:
+		return "ImageOutputComplete", nil
+	case gol.StateChange:
+		return "StateChange", nil
+	case gol.CellFlipped:
+		return "CellFlipped", nil
+	case gol.CellsFlipped:
+		return "CellsFlipped", nil
+	case gol.TurnComplete:
+		return "TurnComplete", nil
+	case gol.FinalTurnComplete:
+		return "FinalTurnComplete", nil
+	case gol.TurnStats:
+		return "TurnStats", nil
+	case gol.CellStateChanged:
+		return "CellStateChanged", nil
+	default:
+		return "", fmt.Errorf("cannot log event of type %T", event)
+	}
+}
+
+// decode converts the payload of a record back into its event
+func decode(r record) (gol.Event, error) {
+	switch r.Type {
+	case "AliveCellsCount":
+		var e gol.AliveCellsCount
+		err := json.Unmarshal(r.Payload, &e)
+		return e, err
+	case "ImageOutputComplete":
+		var e gol.ImageOutputComplete
+		err := json.Unmarshal(r.Payload, &e)
+		return e, err
+	case "StateChange":
+		var e gol.StateChange
+		err := json.Unmarshal(r.Payload, &e)
+		return e, err
+	case "CellFlipped":
+		var e gol.CellFlipped
+		err := json.Unmarshal(r.Payload, &e)
+		return e, err
+	case "CellsFlipped":
+		var e gol.CellsFlipped
+		err := json.Unmarshal(r.Payload, &e)
+		return e, err
+	case "TurnComplete":
+		var e gol.TurnComplete
+		err := json.Unmarshal(r.Payload, &e)
+		return e, err
+	case "FinalTurnComplete":
+		var e gol.FinalTurnComplete
+		err := json.Unmarshal(r.Payload, &e)
+		return e, err
+	case "TurnStats":
+		var e gol.TurnStats
+		err := json.Unmarshal(r.Payload, &e)
+		return e, err
+	case "CellStateChanged":
+		var e gol.CellStateChanged
+		err := json.Unmarshal(r.Payload, &e)
+		return e, err
+	default:
+		return nil, fmt.Errorf("unknown event type %q in log", r.Type)
+	}
+}
+
+// Writer writes events as newline delimited JSON
+type Writer struct {
+	out     *bufio.Writer
+	encoder *json.Encoder
+	start   time.Time
+}
+
+// NewWriter starts a log, writing the Params of the run as the first record
+func NewWriter(w io.Writer, p gol.Params) (*Writer, error) {
+	out := bufio.NewWriter(w)
+	writer := &Writer{out: out, encoder: json.NewEncoder(out), start: time.Now()}
+	payload, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return writer, writer.encoder.Encode(record{Type: paramsType, Payload: payload})
+}
+
+// Write adds one event to the log
+func (writer *Writer) Write(event gol.Event) error {
+	name, err := typeName(event)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return writer.encoder.Encode(record{
+		Type:    name,
+		Turn:    event.GetCompletedTurns(),
+		Millis:  time.Since(writer.start).Nanoseconds() / int64(time.Millisecond),
+		Payload: payload,
+	})
+}
+
+// Flush writes any buffered records to the underlying writer
+func (writer *Writer) Flush() error {
+	return writer.out.Flush()
+}
+
+// Run writes every event to a log file at path until the events channel is closed
+func Run(path string, p gol.Params, events <-chan gol.Event) {
+	file, err := os.Create(path)
+	util.Check(err)
+	defer file.Close()
+	writer, err := NewWriter(file, p)
+	util.Check(err)
+	for event := range events {
+		util.Check(writer.Write(event))
+	}
+	util.Check(writer.Flush())
+}
+
+// Reader reads events back from a log
+type Reader struct {
+	decoder *json.Decoder
+	Params  gol.Params // the Params of the logged run
+}
+
+// NewReader starts reading a log, reading the Params of the run from the first record
+func NewReader(r io.Reader) (*Reader, error) {
+	reader := &Reader{decoder: json.NewDecoder(bufio.NewReader(r))}
+	var header record
+	if err := reader.decoder.Decode(&header); err != nil {
+		return nil, err
+	}
+	if header.Type != paramsType {
+		return nil, errors.New("event log does not start with its Params")
+	}
+	return reader, json.Unmarshal(header.Payload, &reader.Params)
+}
+
+// Next returns the next event and the time it was logged since the start of the run, or io.EOF at the end of the log
+func (reader *Reader) Next() (gol.Event, time.Duration, error) {
+	var r record
+	if err := reader.decoder.Decode(&r); err != nil {
+		return nil, 0, err
+	}
+	event, err := decode(r)
+	return event, time.Duration(r.Millis) * time.Millisecond, err
+}
+
+// Replay sends every event in the log, keeping the pace of the original run scaled by speed, or as fast as
+// possible if speed is 0. 'p' pauses and resumes and 'q' stops the replay, whether it is waiting for an event to be
+// due or for one to be taken. The events channel is closed at the end.
+func Replay(reader *Reader, events chan<- gol.Event, keyPresses <-chan rune, speed float64) {
+	defer close(events)
+	start := time.Now()
+	// pressed handles a key press, returning true if the replay should stop
+	pressed := func(key rune) bool {
+		switch key {
+		case 'q':
+			return true
+		case 'p':
+			pausedAt := time.Now()
+			if waitForUnpause(keyPresses) == 'q' {
+				return true
+			}
+			start = start.Add(time.Since(pausedAt)) // the pause doesn't count towards the pace
+		}
+		return false
+	}
+	for {
+		event, at, err := reader.Next()
+		if err == io.EOF {
+			return
+		}
+		util.Check(err)
+		for speed > 0 && time.Since(start) < time.Duration(float64(at)/speed) { // wait until the event is due
+			select {
+			case <-time.After(time.Duration(float64(at)/speed) - time.Since(start)):
+			case key := <-keyPresses:
+				if pressed(key) {
+					return
+				}
+			}
+		}
+		for sent := false; !sent; {
+			select {
+			case events <- event:
+				sent = true
+			case key := <-keyPresses:
+				if pressed(key) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// waitForUnpause blocks until 'p' or 'q' is pressed, returning which one it was
+func waitForUnpause(keyPresses <-chan rune) rune {
+	for key := range keyPresses {
+		if key == 'p' || key == 'q' {
+			return key
+		}
+	}
+	return 'q'
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/eventlog"
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+// TestEventLog tests that every event of a 16x16 image over 10 turns is the same after writing it to a log and replaying it.
+func TestEventLog(t *testing.T) {
+	p := gol.Params{ImageWidth: 16, ImageHeight: 16, Turns: 10, Threads: 4}
+	var log bytes.Buffer
+	writer, err := eventlog.NewWriter(&log, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := make(chan gol.Event)
+	go gol.Run(p, events, nil)
+	var written []gol.Event
+	for event := range events {
+		written = append(written, event)
+		if err := writer.Write(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := eventlog.NewReader(&log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reader.Params != p {
+		t.Errorf("expected params %+v, got %+v", p, reader.Params)
+	}
+	replayed := make(chan gol.Event)
+	go eventlog.Replay(reader, replayed, nil, 0)
+	i := 0
+	for event := range replayed {
+		if i >= len(written) {
+			t.Fatalf("replay has more than the %v events written", len(written))
+		}
+		if !reflect.DeepEqual(event, written[i]) {
+			t.Fatalf("event %v was %#v, replayed as %#v", i, written[i], event)
+		}
+		i++
+	}
+	if i != len(written) {
+		t.Fatalf("expected %v events to be replayed, got %v", len(written), i)
+	}
+	if _, _, err := reader.Next(); err != io.EOF {
+		t.Errorf("expected the end of the log, got %v", err)
+	}
+}
+
+// TestReplayQuits tests that 'q' stops a replay at speed 0, which is always waiting for its next event to be taken
+func TestReplayQuits(t *testing.T) {
+	p := gol.Params{ImageWidth: 16, ImageHeight: 16, Turns: 10, Threads: 4}
+	var log bytes.Buffer
+	writer, err := eventlog.NewWriter(&log, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := make(chan gol.Event)
+	go gol.Run(p, events, nil)
+	for event := range events {
+		if err := writer.Write(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := eventlog.NewReader(&log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed := make(chan gol.Event)
+	keyPresses := make(chan rune)
+	go eventlog.Replay(reader, replayed, keyPresses, 0)
+	<-replayed
+	select {
+	case keyPresses <- 'q':
+	case <-time.After(time.Second):
+		t.Fatal("the replay should read q whilst it waits for its next event to be taken")
+	}
+	if _, ok := <-replayed; ok {
+		t.Error("the replay should have stopped after q")
+	}
+}
@@ -0,0 +1,142 @@
+package gol
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// EventKind is a set of event types that a subscriber wants to receive
+type EventKind uint
+
+const (
+	AliveCellsCountEvent EventKind = 1 << iota
+	ImageOutputCompleteEvent
+	StateChangeEvent
+	CellFlippedEvent
+	CellsFlippedEvent
+	TurnCompleteEvent
+	FinalTurnCompleteEvent
+	TurnStatsEvent
+	CellStateChangedEvent
+
+	AllEvents     = ^EventKind(0)
+	BoardEvents   = CellFlippedEvent | CellsFlippedEvent | CellStateChangedEvent | TurnCompleteEvent | FinalTurnCompleteEvent // enough to draw the board
+	MessageEvents = AliveCellsCountEvent | ImageOutputCompleteEvent | StateChangeEvent                                        // events with something to print
+)
+
+// KindOf returns the EventKind of an event, or 0 for event types the bus doesn't know about
+func KindOf(event Event) EventKind {
+	switch event.(type) {
+	case AliveCellsCount:
+		return AliveCellsCountEvent
+	case ImageOutputComplete:
+		return ImageOutputCompleteEvent
+	case StateChange:
+		return StateChangeEvent
+	case CellFlipped:
+		return CellFlippedEvent
+	case CellsFlipped:
+		return CellsFlippedEvent
+	case TurnComplete:
+		return TurnCompleteEvent
+	case FinalTurnComplete:
+		return FinalTurnCompleteEvent
+	case TurnStats:
+		return TurnStatsEvent
+	case CellStateChanged:
+		return CellStateChangedEvent
+	default:
+		return 0
+	}
+}
+
+// DropPolicy decides what happens when a subscriber's buffer is full
+type DropPolicy int
+
+const (
+	Block      DropPolicy = iota // wait for the subscriber, which holds up every other subscriber and eventually the workers
+	DropNewest                   // throw away the new event
+	DropOldest                   // throw away the oldest buffered event to make room for the new one
+)
+
+// Subscription is one consumer of the bus
+type Subscription struct {
+	Events  <-chan Event
+	events  chan Event
+	kinds   EventKind
+	policy  DropPolicy
+	dropped uint64
+}
+
+// Dropped returns the number of events thrown away because the subscriber fell behind
+func (subscription *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&subscription.dropped)
+}
+
+// Bus copies every event from the engine to any number of subscribers, each with their own filter and buffer
+type Bus struct {
+	lock          sync.Mutex
+	subscriptions []*Subscription
+}
+
+// NewBus creates a Bus with no subscribers
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe registers a consumer for the given kinds of event. Subscribe before calling Run to see every event.
+func (bus *Bus) Subscribe(kinds EventKind, buffer int, policy DropPolicy) *Subscription {
+	events := make(chan Event, buffer)
+	subscription := &Subscription{Events: events, events: events, kinds: kinds, policy: policy}
+	bus.lock.Lock()
+	bus.subscriptions = append(bus.subscriptions, subscription)
+	bus.lock.Unlock()
+	return subscription
+}
+
+// Run publishes every event from the engine until its channel is closed, then closes every subscriber's channel
+func (bus *Bus) Run(events <-chan Event) {
+	for event := range events {
+		kind := KindOf(event)
+		bus.lock.Lock()
+		for _, subscription := range bus.subscriptions {
+			if subscription.kinds&kind != 0 {
+				subscription.publish(event)
+			}
+		}
+		bus.lock.Unlock()
+	}
+	bus.lock.Lock()
+	for _, subscription := range bus.subscriptions {
+		close(subscription.events)
+	}
+	bus.subscriptions = nil
+	bus.lock.Unlock()
+}
+
+// publish sends an event to one subscriber following its drop policy
+func (subscription *Subscription) publish(event Event) {
+	switch subscription.policy {
+	case Block:
+		subscription.events <- event
+	case DropNewest:
+		select {
+		case subscription.events <- event:
+		default:
+			atomic.AddUint64(&subscription.dropped, 1)
+		}
+	case DropOldest:
+		for {
+			select {
+			case subscription.events <- event:
+				return
+			default:
+			}
+			select {
+			case <-subscription.events: // make room, unless the subscriber has just done so
+				atomic.AddUint64(&subscription.dropped, 1)
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package gol
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"strings"
+)
+
+// colourRules are the names of the rules where alive cells have colours, by their number of colours. A name on its
+// own is Conway's Game of Life, and after a rule in B/S notation it gives that rule colours, e.g. "B36/S23QuadLife".
+var colourRules = map[int]string{2: "Immigration", 4: "QuadLife"}
+
+// palette holds the colours alive cells are drawn in, in order
+var palette = []color.RGBA{{R: 0xFF, G: 0x40, B: 0x40, A: 0xFF}, {R: 0x40, G: 0x80, B: 0xFF, A: 0xFF}, {R: 0x40, G: 0xE0, B: 0x40, A: 0xFF}, {R: 0xFF, G: 0xE0, B: 0x40, A: 0xFF}}
+
+// parseColours reads a rule whose alive cells have colours, which is a Life-like rule followed by Immigration for 2
+// colours or QuadLife for 4, e.g. "Immigration" or "B36/S23QuadLife"
+func parseColours(rulestring string) (Rule, bool, error) {
+	for colours, name := range colourRules {
+		if !strings.HasSuffix(strings.ToLower(rulestring), strings.ToLower(name)) {
+			continue
+		}
+		rule, err := ParseRule(rulestring[:len(rulestring)-len(name)])
+		if err != nil {
+			return rule, true, err
+		}
+		if rule.States != 2 || rule.extended() || rule.Lattice != Square || rule.transitions != nil || rule.Voxels || rule.Margolus != nil || rule.Colours != 0 {
+			return rule, true, fmt.Errorf("%v can only give colours to Life-like rules with two states, not %v", name, rule)
+		}
+		rule.Colours = colours
+		return rule, true, nil
+	}
+	return Rule{}, false, nil
+}
+
+// coloursString writes a rule with colours as the name for its number of colours, after the rule it gives colours to
+// unless that is Conway's Game of Life
+func coloursString(rule Rule) string {
+	name := colourRules[rule.Colours]
+	rule.Colours = 0
+	if rule.String() == Conway.String() {
+		return name
+	}
+	return rule.String() + name
+}
+
+// checkColours makes sure a game with colours is on a board that keeps them
+func checkColours(p Params, rule Rule) error {
+	if rule.Colours > 0 && p.Unbounded {
+		return errors.New("a rule with colours can't be used on an unbounded board")
+	}
+	return nil
+}
+
+// colourShade returns the grey level a colour is saved and sent as, from 255 for the first colour down in even steps
+func colourShade(colour int, colours int) uint8 {
+	return uint8(255 - colour*256/colours)
+}
+
+// shadeColour returns the colour with the nearest grey level to a shade that isn't dead
+func shadeColour(shade uint8, colours int) int {
+	return minInt(((255-int(shade))*colours+128)/256, colours-1)
+}
+
+// Palette returns the colours cells are drawn in for a rule with colours, indexed by PaletteIndex: black for dead
+// cells followed by the colour of each colour of alive cell. Rules without colours have no palette.
+func (rule Rule) Palette() color.Palette {
+	if rule.Colours == 0 {
+		return nil
+	}
+	colours := color.Palette{color.Black}
+	for colour := 0; colour < rule.Colours; colour++ {
+		colours = append(colours, palette[colour])
+	}
+	return colours
+}
+
+// PaletteIndex returns the position in Palette of a cell with a grey level from an image or CellStateChanged event
+func (rule Rule) PaletteIndex(shade uint8) uint8 {
+	if shade == 0 || rule.Colours == 0 {
+		return 0
+	}
+	return uint8(shadeColour(shade, rule.Colours) + 1)
+}
+
+// addColours gives a board somewhere to keep the colour of each alive cell, for rules with colours
+func (board *Board) addColours(colours int) {
+	if colours == 0 {
+		return
+	}
+	board.colours = colours
+	board.shades = make([][]uint8, board.height)
+	for y := range board.shades {
+		board.shades[y] = make([]uint8, board.width)
+	}
+}
+
+// Shade returns the grey level a cell is saved and sent as, which is the shade of its colour if it is alive and the
+// rule has colours, or its value otherwise
+func (board *Board) Shade(x int, y int) uint8 {
+	value := board.Get(x, y)
+	if board.shades != nil && value == 255 {
+		return board.shades[y][x]
+	}
+	return value
+}
+
+// SetShade sets a cell from the grey level it is saved and sent as. For rules with colours, a cell that isn't dead
+// is alive with the colour nearest its grey level.
+func (board *Board) SetShade(x int, y int, shade uint8) {
+	if board.shades == nil || shade == 0 {
+		board.Set(x, y, shade)
+		return
+	}
+	board.Set(x, y, 255)
+	board.shades[y][x] = colourShade(shadeColour(shade, board.colours), board.colours)
+}
+
+// colourCell sets the colour of a cell after a turn, for rules with colours. Alive cells keep their colour, and born
+// cells take the colour most of the alive cells around them have. Ties go to the one colour none of them have if
+// there is just one, as when QuadLife gives birth from three cells of different colours, or else the first of them.
+func (game *Game) colourCell(x int, y int, value uint8, newValue uint8) {
+	board := game.current
+	if board.shades == nil || newValue != 255 {
+		return
+	}
+	if value == 255 {
+		game.advanced.shades[y][x] = board.shades[y][x]
+		return
+	}
+	var parents [4]int // by colour, which is enough for QuadLife
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			cellX, cellY := wrap(x+dx, board.width), wrap(y+dy, board.height)
+			if (dx != 0 || dy != 0) && board.Alive(cellX, cellY, false) {
+				parents[shadeColour(board.shades[cellY][cellX], board.colours)]++
+			}
+		}
+	}
+	best, tied, missing := 0, false, -1
+	for colour := 0; colour < board.colours; colour++ {
+		switch {
+		case parents[colour] > parents[best]:
+			best, tied = colour, false
+		case colour != best && parents[colour] == parents[best]:
+			tied = true
+		}
+		if parents[colour] == 0 {
+			if missing == -1 {
+				missing = colour
+			} else {
+				missing = -2 // more than one colour is missing
+			}
+		}
+	}
+	if tied && missing >= 0 {
+		best = missing
+	}
+	game.advanced.shades[y][x] = colourShade(best, board.colours)
+}
+
+// ColourCounts returns the number of alive cells of each colour, or nil if the rule has no colours
+func (game *Game) ColourCounts() []int {
+	board := game.current
+	if board.shades == nil {
+		return nil
+	}
+	counts := make([]int, board.colours)
+	for j := 0; j < board.height; j++ {
+		for i := 0; i < board.width; i++ {
+			if board.Alive(i, j, false) {
+				counts[shadeColour(board.shades[j][i], board.colours)]++
+			}
+		}
+	}
+	return counts
+}
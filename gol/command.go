@@ -0,0 +1,27 @@
+package gol
+
+// Command is a request to change a running game, sent to RunWithCommands.
+// Commands are applied by the engine between turns, or straight away whilst the game is paused.
+type Command interface {
+	apply(game *Game)
+}
+
+// StampPattern is a Command that places a pattern onto the board with its top left corner at X, Y.
+type StampPattern struct {
+	Pattern  Pattern
+	X, Y     int
+	Rotation Rotation
+}
+
+func (command StampPattern) apply(game *Game) {
+	game.Stamp(command.Pattern, command.X, command.Y, command.Rotation)
+}
+
+// SetThreads is a Command that changes the number of worker threads used from the next turn on.
+type SetThreads struct {
+	Threads int
+}
+
+func (command SetThreads) apply(game *Game) {
+	game.SetThreads(command.Threads)
+}
@@ -0,0 +1,36 @@
+package gol
+
+import "uk.ac.bris.cs/gameoflife/util"
+
+// Crop passes every event from in to out, apart from flipped cells outside width by height, so consumers that
+// draw a fixed size board can follow an unbounded game. It closes out once in is closed.
+func Crop(in <-chan Event, out chan<- Event, width int, height int) {
+	inside := func(cell util.Cell) bool {
+		return cell.X >= 0 && cell.Y >= 0 && cell.X < width && cell.Y < height
+	}
+	for event := range in {
+		switch e := event.(type) {
+		case CellFlipped:
+			if !inside(e.Cell) {
+				continue
+			}
+		case CellStateChanged:
+			if !inside(e.Cell) {
+				continue
+			}
+		case CellsFlipped:
+			var cells []util.Cell
+			for _, cell := range e.Cells {
+				if inside(cell) {
+					cells = append(cells, cell)
+				}
+			}
+			if len(cells) == 0 {
+				continue
+			}
+			event = CellsFlipped{CompletedTurns: e.CompletedTurns, Cells: cells}
+		}
+		out <- event
+	}
+	close(out)
+}
@@ -9,20 +9,28 @@ import (
 )
 
 type distributorChannels struct {
-	events     chan<- Event
-	ioCommand  chan<- ioCommand
-	ioIdle     <-chan bool
-	ioFilename chan<- string
-	ioOutput   chan<- uint8
-	ioInput    <-chan uint8
-	keys       <-chan rune
+	events       chan<- Event
+	ioCommand    chan<- ioCommand
+	ioIdle       <-chan bool
+	ioFilename   chan<- string
+	ioOutputSize chan<- imageSize
+	ioOutput     chan<- uint8
+	ioInput      <-chan uint8
+	keys         <-chan rune
+	commands     <-chan Command
 }
 
 // Board stores one game of life board and its width/height
 type Board struct {
-	cells  [][]uint8
-	width  int // do we need all of these?
-	height int // do we need all of these?
+	cells     [][]uint8
+	width     int // do we need all of these?
+	height    int // do we need all of these?
+	tiles     []tile
+	tilesX    int
+	tilesY    int
+	tileAlive []int     // number of cells that aren't dead in each tile, so tiles with nothing around them can be skipped
+	colours   int       // the number of colours alive cells can be, see Rule.Colours
+	shades    [][]uint8 // the grey level of the colour of each alive cell, only for rules with colours
 }
 
 // Game stores the state of the boards, events and details about the ongoing game
@@ -31,8 +39,23 @@ type Game struct {
 	advanced       *Board // the current board after one turn
 	completedTurns int
 	raceMutex      sync.Mutex
+	endOnce        sync.Once // quitting and finishing the last turn can happen at once, but gameOver can only be closed once
 	paused         bool
 	events         chan<- Event
+	batchEvents    bool   // send one CellsFlipped per tile instead of one CellFlipped per cell
+	hooks          *Hooks // may be nil
+	turnStats      bool
+	workerTimes    []time.Duration // how long each worker took on the last turn
+	tileChanged    []bool          // whether each tile changed on the last turn, so only tiles around changes are advanced
+	universe       *Universe       // used instead of the boards when the game is unbounded
+	threads        int             // number of workers, which can be changed between turns
+	pool           *workerPool     // the workers, which are kept between turns
+	area           *summedArea     // the current board as a summedArea, built each turn for extended rules
+	rule           Rule
+	noise          float64 // see Params.Noise
+	seed           int64
+	volume         *Volume // used instead of the boards when the rule is 3D
+	slice          int     // the slice of the volume that events are sent for
 }
 
 // createBoard creates a board struct given a width and height
@@ -42,38 +65,112 @@ func createBoard(width int, height int) *Board {
 	for x := range cells {
 		cells[x] = make([]uint8, width)
 	}
-	return &Board{
+	board := &Board{
 		cells:  cells,
 		width:  width,
 		height: height,
 	}
+	board.createTiles()
+	return board
+}
+
+// inputFilename returns the name of the image the board is read from, which holds every slice of a 3D board one
+// under another, e.g. "64x64" or "32x32x16"
+func inputFilename(p Params) string {
+	filename := strconv.Itoa(p.ImageWidth) + "x" + strconv.Itoa(p.ImageHeight)
+	if p.Depth > 0 {
+		filename += "x" + strconv.Itoa(p.Depth)
+	}
+	return filename
 }
 
 // createGame creates an instance of Game
-func createGame(width int, height int, c distributorChannels) *Game {
-	current := createBoard(width, height)
-	current.PopulateBoard(c) // set the cells of the current board to those from the input
-	advanced := createBoard(width, height)
-	return &Game{
+func createGame(p Params, c distributorChannels) *Game {
+	rule, err := ParseRule(p.Rule)
+	util.Check(err)
+	util.Check(checkParams(p, rule))
+	c.ioCommand <- ioInput // start reading the image
+	c.ioFilename <- inputFilename(p)
+	current := createBoard(p.ImageWidth, p.ImageHeight)
+	current.addColours(rule.Colours)
+	var alive []util.Cell
+	var volume *Volume
+	if rule.Voxels { // the boards are left empty
+		volume = newVolume(p.ImageWidth, p.ImageHeight, p.Depth)
+		for i := range volume.cells {
+			volume.cells[i] = <-c.ioInput
+		}
+		alive = volume.AliveCells(wrap(p.Slice, p.Depth))
+	} else {
+		alive = current.PopulateBoard(c) // set the cells of the current board to those from the input
+	}
+	advanced := createBoard(p.ImageWidth, p.ImageHeight)
+	advanced.addColours(rule.Colours)
+	game := &Game{
 		current:        current,
 		advanced:       advanced,
 		completedTurns: 0,
 		events:         c.events,
 		paused:         false,
+		batchEvents:    p.BatchEvents,
+		hooks:          p.Hooks,
+		turnStats:      p.TurnStats,
+		threads:        p.Threads,
+		rule:           rule,
+		noise:          p.Noise,
+		seed:           p.Seed,
+		volume:         volume,
+	}
+	if volume != nil {
+		game.slice = wrap(p.Slice, p.Depth)
+	}
+	if p.Unbounded { // the image is loaded into the top left of the universe
+		game.universe = newUniverse()
+		for _, cell := range alive {
+			game.universe.Set(cell.X, cell.Y, 255)
+		}
+	}
+	game.tileChanged = make([]bool, len(current.tiles))
+	for i := range game.tileChanged { // nothing is known about the board yet, so every tile is advanced on the first turn
+		game.tileChanged[i] = true
+	}
+	if rule.Colours > 0 { // a flip can't say what colour a cell is
+		for _, cell := range alive {
+			game.cellChanged(cell, current.Shade(cell.X, cell.Y), nil)
+		}
+		return game
 	}
+	game.SendFlips(alive) // when first loading the board, send the event for all cells that are alive
+	return game
 }
 
-// PopulateBoard sets the board values to those from the input
-func (board *Board) PopulateBoard(c distributorChannels) {
+// PopulateBoard sets the board values to those from the input, returning the cells that are alive
+func (board *Board) PopulateBoard(c distributorChannels) []util.Cell {
+	var alive []util.Cell
 	for j := 0; j < board.height; j++ {
 		for i := 0; i < board.width; i++ {
-			value := <-c.ioInput
-			board.Set(i, j, value)
-			if value == 255 { // when first loading the board, send the event for all cells that are alive
-				c.events <- CellFlipped{Cell: util.Cell{X: i, Y: j}}
+			board.SetShade(i, j, <-c.ioInput)
+			if board.Get(i, j) == 255 {
+				alive = append(alive, util.Cell{X: i, Y: j})
 			}
 		}
 	}
+	board.countTiles()
+	return alive
+}
+
+// SendFlips sends the cells that have changed state, as one CellsFlipped event if batching or one CellFlipped each otherwise
+func (game *Game) SendFlips(cells []util.Cell) {
+	if len(cells) == 0 {
+		return
+	}
+	if game.batchEvents {
+		game.events <- CellsFlipped{CompletedTurns: game.completedTurns, Cells: cells}
+		return
+	}
+	for _, cell := range cells {
+		game.events <- CellFlipped{CompletedTurns: game.completedTurns, Cell: cell}
+	}
 }
 
 // Get retrieves the value of a cell
@@ -95,75 +192,85 @@ func (board *Board) Alive(x int, y int, wrap bool) bool {
 	return board.Get(x, y) == 255
 }
 
-// Neighbours checks all cells within 1 cell, then checks if each of these are alive to get the returned neighbour count
-func (board *Board) Neighbours(x int, y int) int {
-	aliveNeighbours := 0
+// NeighbourhoodBits returns the 3x3 neighbourhood of a cell as bits, accounting for wrap around, so the rule can look
+// up what happens to it without counting
+func (board *Board) NeighbourhoodBits(x int, y int) int {
+	bits := 0
 	for i := -1; i <= 1; i++ {
 		for j := -1; j <= 1; j++ {
-			if i == 0 && j == 0 { // ensure we aren't counting the cell itself
-				continue
-			}
-			if board.Alive(x+j, y+i, true) { // note: we are sorta repeating this unnecessarily for each cell?
-				aliveNeighbours++
+			if board.Alive(x+j, y+i, true) {
+				bits |= 1 << uint((i+1)*3+j+1)
 			}
 		}
 	}
-	return aliveNeighbours
+	return bits
 }
 
-// AdvanceCell updates the value for a specific cell after a turn
-func (game *Game) AdvanceCell(x int, y int) {
-	aliveNeighbours := game.current.Neighbours(x, y)
-	var newCellValue uint8
-	if game.current.Alive(x, y, false) { // if the cell is alive
-		if aliveNeighbours < 2 || aliveNeighbours > 3 {
-			newCellValue = 0 // dies
-			game.events <- CellFlipped{CompletedTurns: game.completedTurns, Cell: util.Cell{X: x, Y: y}}
-		} else {
-			newCellValue = 255 // stays the same
-		}
-	} else { // if the cell is dead
-		if aliveNeighbours == 3 {
-			newCellValue = 255 // becomes alive
-			game.events <- CellFlipped{CompletedTurns: game.completedTurns, Cell: util.Cell{X: x, Y: y}}
-		} else {
-			newCellValue = 0 // stays the same
-		}
+// SlideBits moves the neighbourhood bits of the cell to the left of x, y on to x, y, so only the 3 cells in the new
+// right column are read
+func (board *Board) SlideBits(bits int, x int, y int) int {
+	bits = bits >> 1 &^ rightColumn
+	if board.Alive(x+1, y-1, true) {
+		bits |= 1 << 2
+	}
+	if board.Alive(x+1, y, true) {
+		bits |= 1 << 5
+	}
+	if board.Alive(x+1, y+1, true) {
+		bits |= 1 << 8
 	}
+	return bits
+}
+
+// AdvanceCell updates the value for a specific cell after a turn following the rule, given its neighbourhood bits,
+// returning whether the cell changed
+func (game *Game) AdvanceCell(x int, y int, bits int) bool {
+	value := game.current.Get(x, y)
+	newCellValue := game.noisy(x, y, value, game.rule.lookup(value, bits))
 	game.advanced.Set(x, y, newCellValue)
+	game.colourCell(x, y, value, newCellValue)
+	return newCellValue != value
 }
 
-// AdvanceSection advances the board one turn only between the specified x and y values assigned to the worker
-func (game *Game) AdvanceSection(startX int, endX int, startY int, endY int) {
+// AdvanceSection advances the board one turn only between the specified x and y values, returning whether any cell changed
+func (game *Game) AdvanceSection(startX int, endX int, startY int, endY int) bool {
+	switch {
+	case game.rule.transitions != nil:
+		return game.AdvanceTableSection(startX, endX, startY, endY)
+	case game.rule.Margolus != nil:
+		return game.AdvanceMargolusSection(startX, endX, startY, endY)
+	case game.rule.Lattice != Square:
+		return game.AdvanceLatticeSection(startX, endX, startY, endY)
+	case game.rule.extended():
+		return game.AdvanceLargerSection(startX, endX, startY, endY)
+	}
+	var flipped []util.Cell // only used when batching, otherwise each flip is sent straight away
+	changed := false
 	for j := startY; j < endY; j++ {
+		bits := game.current.NeighbourhoodBits(startX, j)
 		for i := startX; i < endX; i++ {
-			game.AdvanceCell(i, j)
+			if i > startX {
+				bits = game.current.SlideBits(bits, i, j)
+			}
+			if game.AdvanceCell(i, j, bits) {
+				changed = true
+				game.cellChanged(util.Cell{X: i, Y: j}, game.advanced.Shade(i, j), &flipped)
+			}
 		}
 	}
+	game.SendFlips(flipped)
+	return changed
 }
 
-// SpawnAdvanceWorker updates game.advanced based on game.current, from startY up to endY
-func (game *Game) SpawnAdvanceWorker(wg *sync.WaitGroup, startX int, endX int, startY int, endY int) {
-	defer wg.Done()
-	game.AdvanceSection(startX, endX, startY, endY)
-}
-
-// Advance splits the board into horizontal slices. Each worker works on one section to advance the whole board one turn
-func (game *Game) Advance(wg *sync.WaitGroup, workers int, width int, height int) {
-	for i := 0; i < workers; i++ {
-		var (
-			startX = 0
-			endX   = width
-			startY = i * height / workers
-			endY   int
-		)
-		if i == workers-1 { // make the last worker take the remaining space
-			endY = height
-		} else {
-			endY = (i + 1) * height / workers
-		}
-		wg.Add(1)
-		go game.SpawnAdvanceWorker(wg, startX, endX, startY, endY) // start a worker
+// cellChanged sends the event for a cell changing to value, or adds it to flipped to be sent later when batching
+func (game *Game) cellChanged(cell util.Cell, value uint8, flipped *[]util.Cell) {
+	switch {
+	case game.rule.States > 2 || game.rule.Colours > 0: // a flip can't say whether a cell is dying or what colour it is
+		game.events <- CellStateChanged{CompletedTurns: game.completedTurns, Cell: cell, State: value}
+	case game.batchEvents:
+		*flipped = append(*flipped, cell)
+	default:
+		game.events <- CellFlipped{CompletedTurns: game.completedTurns, Cell: cell}
 	}
 }
 
@@ -177,15 +284,7 @@ func (game *Game) MonitorAliveCellCount(gameOver chan struct{}, pauseTicker chan
 			<-pauseTicker // wait until it's un-paused
 		case <-ticker.C: // 2 seconds has passed
 			game.raceMutex.Lock() // acquire lock in case count occurring during board swaps
-			count := 0
-			for j := 0; j < game.current.height; j++ { // count number of alive cells
-				for i := 0; i < game.current.width; i++ {
-					if game.current.Alive(i, j, false) {
-						count++
-					}
-				}
-			}
-			game.events <- AliveCellsCount{game.completedTurns, count}
+			game.events <- AliveCellsCount{game.completedTurns, game.AliveCount(), game.ColourCounts()}
 			game.raceMutex.Unlock()
 		case <-gameOver: // check if game is over
 			ticker.Stop()
@@ -194,6 +293,37 @@ func (game *Game) MonitorAliveCellCount(gameOver chan struct{}, pauseTicker chan
 	}
 }
 
+// AliveCount returns the number of alive cells
+func (game *Game) AliveCount() int {
+	if game.universe != nil {
+		return game.universe.Count()
+	}
+	if game.volume != nil { // every slice, not just the one being shown
+		return game.volume.Count()
+	}
+	count := 0
+	for j := 0; j < game.current.height; j++ { // count number of alive cells
+		for i := 0; i < game.current.width; i++ {
+			if game.current.Alive(i, j, false) {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// AliveCells returns a list of Cells that are alive in the game, from the universe if it is unbounded, or from the
+// slice being shown of a 3D game
+func (game *Game) AliveCells() []util.Cell {
+	if game.universe != nil {
+		return game.universe.AliveCells()
+	}
+	if game.volume != nil {
+		return game.volume.AliveCells(game.slice)
+	}
+	return game.current.AliveCells()
+}
+
 // AliveCells returns a list of Cells that are alive at the end of the game
 func (board *Board) AliveCells() []util.Cell {
 	var aliveCells []util.Cell
@@ -207,17 +337,35 @@ func (board *Board) AliveCells() []util.Cell {
 	return aliveCells
 }
 
-// WriteImage outputs the final state of the board as a PGM image
+// WriteImage outputs the final state of the board as a PGM image.
+// An unbounded game outputs the smallest rectangle holding every alive cell, or the size of the input if none are.
+// A 3D game outputs every slice one under another, named like its input with the turn after it.
 func (game *Game) WriteImage(p Params, c distributorChannels) {
+	start := time.Now()
+	game.raceMutex.Lock()     // make sure current isn't being swapped whilst we output
+	get := game.current.Shade // the grey level of its colour for an alive cell of a rule with colours
+	low, high := util.Cell{}, util.Cell{X: p.ImageWidth, Y: p.ImageHeight}
+	if game.universe != nil {
+		get = game.universe.Get
+		if boundsLow, boundsHigh, ok := game.universe.Bounds(); ok {
+			low, high = boundsLow, boundsHigh
+		}
+	}
+	filename := strconv.Itoa(high.X-low.X) + "x" + strconv.Itoa(high.Y-low.Y) + "x" + strconv.Itoa(game.completedTurns)
+	if game.volume != nil { // every slice, one under another
+		get = game.volume.Get
+		high.Y = p.ImageHeight * p.Depth
+		filename = inputFilename(p) + "x" + strconv.Itoa(game.completedTurns)
+	}
 	c.ioCommand <- ioOutput
-	filename := strconv.Itoa(p.ImageWidth) + "x" + strconv.Itoa(p.ImageHeight) + "x" + strconv.Itoa(game.completedTurns)
 	c.ioFilename <- filename
-	game.raceMutex.Lock() // make sure current isn't being swapped whilst we output
-	for j := 0; j < p.ImageHeight; j++ {
-		for i := 0; i < p.ImageWidth; i++ {
-			c.ioOutput <- game.current.Get(i, j)
+	c.ioOutputSize <- imageSize{width: high.X - low.X, height: high.Y - low.Y}
+	for j := low.Y; j < high.Y; j++ {
+		for i := low.X; i < high.X; i++ {
+			c.ioOutput <- get(i, j)
 		}
 	}
+	game.hooks.imageOutput(time.Since(start))
 	game.events <- ImageOutputComplete{game.completedTurns, filename}
 	game.raceMutex.Unlock()
 }
@@ -230,15 +378,21 @@ func (game *Game) MonitorKeyPresses(p Params, c distributorChannels, gameOver ch
 		case 's': // save image
 			game.WriteImage(p, c)
 		case 'q': // quit
-			close(gameOver)
+			game.endGame(gameOver)
 			return
+		case '[': // one less worker
+			game.ChangeThreads(-1)
+		case ']': // one more worker
+			game.ChangeThreads(1)
 		case 'p': // pause game
 			if game.paused {
 				fmt.Println("Continuing")
 				game.paused = false
+				game.events <- StateChange{game.completedTurns, Executing}
 			} else {
 				fmt.Println("Paused after turn: ", game.completedTurns)
 				game.paused = true
+				game.events <- StateChange{game.completedTurns, Paused}
 			}
 			pauseTurns <- game.paused
 			pauseTicker <- game.paused
@@ -246,55 +400,142 @@ func (game *Game) MonitorKeyPresses(p Params, c distributorChannels, gameOver ch
 	}
 }
 
-func (game *Game) ExecuteTurns(gameOver chan struct{}, p Params, pauseTurns chan bool) {
+// SetThreads changes the number of workers used from the next turn on, keeping at least one
+func (game *Game) SetThreads(threads int) {
+	if threads < 1 {
+		threads = 1
+	}
+	game.raceMutex.Lock() // the turns read the number of workers from a different goroutine
+	game.threads = threads
+	game.raceMutex.Unlock()
+	fmt.Println("Threads:", threads)
+}
+
+// ChangeThreads adds to or removes from the number of workers used from the next turn on
+func (game *Game) ChangeThreads(change int) {
+	game.raceMutex.Lock()
+	game.threads += change
+	if game.threads < 1 {
+		game.threads = 1
+	}
+	threads := game.threads
+	game.raceMutex.Unlock()
+	fmt.Println("Threads:", threads)
+}
+
+// ApplyCommands applies every command that is waiting, without blocking
+func (game *Game) ApplyCommands(commands <-chan Command) {
+	for {
+		select {
+		case command := <-commands:
+			command.apply(game)
+		default:
+			return
+		}
+	}
+}
+
+// WaitForUnpause blocks until the game is un-paused, applying any commands that arrive in the meantime
+func (game *Game) WaitForUnpause(pauseTurns chan bool, commands <-chan Command) {
+	for {
+		select {
+		case <-pauseTurns:
+			return
+		case command := <-commands:
+			command.apply(game)
+		}
+	}
+}
+
+func (game *Game) ExecuteTurns(gameOver chan struct{}, p Params, pauseTurns chan bool, commands <-chan Command) {
 	var wg sync.WaitGroup
+	defer game.stopWorkers()
 	for game.completedTurns < p.Turns { // execute the turns
 		select {
 		case <-pauseTurns: // if it's paused
-			<-pauseTurns // wait until it's un-paused
+			game.WaitForUnpause(pauseTurns, commands) // wait until it's un-paused
 		case <-gameOver:
 			return
 		default:
 		}
-		game.Advance(&wg, p.Threads, p.ImageWidth, p.ImageHeight)
+		game.ApplyCommands(commands) // commands can only change the board between turns
+		start := time.Now()
+		game.raceMutex.Lock()
+		threads := game.threads
+		game.raceMutex.Unlock()
+		switch {
+		case game.universe != nil:
+			game.AdvanceUniverse(&wg, threads)
+		case game.volume != nil:
+			game.AdvanceVolume(&wg, threads)
+		default:
+			game.Advance(&wg, threads)
+		}
 		wg.Wait() // wait until all goroutines are done for this turn
 
 		game.raceMutex.Lock() // lock in case count occurring during board swaps
-		// we swap the boards since the old advanced is current, and we will update all cells of the new advanced anyway
-		game.current, game.advanced = game.advanced, game.current
+		switch {
+		case game.universe != nil:
+			game.universe.Swap()
+		case game.volume != nil:
+			game.volume.Swap()
+		default:
+			// we swap the boards since the old advanced is current, and we will update all cells of the new advanced anyway
+			game.current, game.advanced = game.advanced, game.current
+		}
 		game.completedTurns++
 		game.raceMutex.Unlock()
+		step := time.Since(start)
+		game.hooks.turnComplete(game.completedTurns, step)
+		if game.turnStats {
+			game.events <- game.TurnStats(step)
+		}
 		game.events <- TurnComplete{game.completedTurns}
 	}
-	close(gameOver) // all turns executed
+	game.endGame(gameOver) // all turns executed
+}
+
+// endGame closes gameOver to tell everything the game is over, unless it has already been closed
+func (game *Game) endGame(gameOver chan struct{}) {
+	game.endOnce.Do(func() {
+		close(gameOver)
+	})
+}
+
+// TurnStats builds the TurnStats event for the last turn from the time each worker took
+func (game *Game) TurnStats(step time.Duration) TurnStats {
+	workers := make([]time.Duration, len(game.workerTimes))
+	copy(workers, game.workerTimes)
+	slowest, fastest := workers[0], workers[0]
+	for _, duration := range workers {
+		if duration > slowest {
+			slowest = duration
+		}
+		if duration < fastest {
+			fastest = duration
+		}
+	}
+	return TurnStats{CompletedTurns: game.completedTurns, Step: step, Workers: workers, Imbalance: slowest - fastest}
 }
 
 // distributor divides the work between workers and interacts with other goroutines.
 func distributor(p Params, c distributorChannels) {
-	// make the filename and pass it through channel
-	var filename string
-	filename = strconv.Itoa(p.ImageWidth) + "x" + strconv.Itoa(p.ImageHeight)
-	c.ioCommand <- ioInput   // start reading the image
-	c.ioFilename <- filename // pass the filename of the image
+	game := createGame(p, c) // reads the image
 
-	game := createGame(p.ImageWidth, p.ImageHeight, c)
+	// the flips so far were of the image rather than a turn, which viewers that save every turn need to know
+	game.events <- StateChange{game.completedTurns, Executing}
 
 	gameOver := make(chan struct{}) // signals game is over
 	pauseTurns := make(chan bool)   // paused
 	pauseTicker := make(chan bool)  // paused
 	go game.MonitorAliveCellCount(gameOver, pauseTicker)
 	go game.MonitorKeyPresses(p, c, gameOver, pauseTurns, pauseTicker)
-	go game.ExecuteTurns(gameOver, p, pauseTurns)
+	go game.ExecuteTurns(gameOver, p, pauseTurns, c.commands)
 
 	<-gameOver // wait until turns are done executing
-	select {
-	case <-gameOver:
-	default: // channel has not been closed so we close it
-		close(gameOver) // broadcasts to everything
-	}
 
 	game.WriteImage(p, c)
-	aliveCells := game.current.AliveCells()
+	aliveCells := game.AliveCells()
 	game.events <- FinalTurnComplete{game.completedTurns, aliveCells}
 
 	// Make sure that the Io has finished any output before exiting.
@@ -2,6 +2,8 @@ package gol
 
 import (
 	"fmt"
+	"time"
+
 	"uk.ac.bris.cs/gameoflife/util"
 )
 
@@ -19,6 +21,7 @@ type Event interface {
 type AliveCellsCount struct { // implements Event
 	CompletedTurns int
 	CellsCount     int
+	ColourCounts   []int // the number of alive cells of each colour for rules with colours such as Immigration, or nil
 }
 
 // ImageOutputComplete is an Event notifying the user about the completion of output.
@@ -38,7 +41,7 @@ const (
 )
 
 // StateChange is an Event notifying the user about the change of state of execution.
-// This Event should be sent every time the execution is paused, resumed or quit.
+// This Event should be sent every time the execution is paused, resumed or quit, and once the image has been loaded.
 type StateChange struct { // implements Event
 	CompletedTurns int
 	NewState       State
@@ -52,6 +55,24 @@ type CellFlipped struct { // implements Event
 	Cell           util.Cell
 }
 
+// CellsFlipped is an Event notifying the GUI about a change of state of many cells at once.
+// When Params.BatchEvents is set, this is sent once per tile each turn instead of a CellFlipped for each cell.
+// Like CellFlipped, all CellsFlipped events must be sent *before* TurnComplete.
+type CellsFlipped struct { // implements Event
+	CompletedTurns int
+	Cells          []util.Cell
+}
+
+// CellStateChanged is an Event notifying the GUI about a cell changing to a new state, for rules with more than two
+// states where a cell can be dying as well as alive or dead, and rules with colours, where alive cells are sent as the
+// shade of their colour. It is sent instead of CellFlipped for these rules.
+// Like CellFlipped, all CellStateChanged events must be sent *before* TurnComplete.
+type CellStateChanged struct { // implements Event
+	CompletedTurns int
+	Cell           util.Cell
+	State          uint8 // the grey level the cell is stored as, 0 when dead, 255 when alive and in between when dying or coloured
+}
+
 // TurnComplete is an Event notifying the GUI about turn completion.
 // SDL will render a frame when this event is sent.
 // All CellFlipped events must be sent *before* TurnComplete.
@@ -59,6 +80,15 @@ type TurnComplete struct { // implements Event
 	CompletedTurns int
 }
 
+// TurnStats is an Event with the timings of one turn, used for tuning the number of threads and how the board is split.
+// This Event is only sent when Params.TurnStats is set, just before the TurnComplete of the same turn.
+type TurnStats struct { // implements Event
+	CompletedTurns int
+	Step           time.Duration   // time taken to advance the whole board
+	Workers        []time.Duration // time taken by each worker to advance its section
+	Imbalance      time.Duration   // difference between the slowest and the fastest worker
+}
+
 // FinalTurnComplete is an Event notifying the testing framework about the new world state after execution finished.
 // The data included with this Event is used directly by the tests.
 // SDL closes the window when this Event is sent.
@@ -91,6 +121,9 @@ func (event StateChange) GetCompletedTurns() int {
 }
 
 func (event AliveCellsCount) String() string {
+	if event.ColourCounts != nil {
+		return fmt.Sprintf("Alive Cells %v, by colour %v", event.CellsCount, event.ColourCounts)
+	}
 	return fmt.Sprintf("Alive Cells %v", event.CellsCount)
 }
 
@@ -114,6 +147,22 @@ func (event CellFlipped) GetCompletedTurns() int {
 	return event.CompletedTurns
 }
 
+func (event CellsFlipped) String() string {
+	return fmt.Sprintf("")
+}
+
+func (event CellsFlipped) GetCompletedTurns() int {
+	return event.CompletedTurns
+}
+
+func (event CellStateChanged) String() string {
+	return fmt.Sprintf("")
+}
+
+func (event CellStateChanged) GetCompletedTurns() int {
+	return event.CompletedTurns
+}
+
 func (event TurnComplete) String() string {
 	return fmt.Sprintf("")
 }
@@ -122,6 +171,14 @@ func (event TurnComplete) GetCompletedTurns() int {
 	return event.CompletedTurns
 }
 
+func (event TurnStats) String() string {
+	return fmt.Sprintf("")
+}
+
+func (event TurnStats) GetCompletedTurns() int {
+	return event.CompletedTurns
+}
+
 func (event FinalTurnComplete) String() string {
 	return fmt.Sprintf("")
 }
@@ -6,14 +6,60 @@ type Params struct {
 	Threads     int
 	ImageWidth  int
 	ImageHeight int
+	BatchEvents bool    // send CellsFlipped events instead of CellFlipped, see Unbatch for consumers that need CellFlipped
+	TurnStats   bool    // send a TurnStats event with the timings of every turn
+	Unbounded   bool    // grow the board instead of wrapping around the edges, starting with the image in the top left
+	Rule        string  // the rulestring, see ParseRule, where "" is Conway's Game of Life
+	Noise       float64 // the probability of each cell doing the opposite of what the rule says on each turn
+	Seed        int64   // decides which cells the noise changes, so runs with the same seed are the same whatever the threads
+	Depth       int     // the number of slices of a board for 3D rules, read from an image of the slices one under another
+	Slice       int     // the slice of a 3D board that events are sent for, so viewers show that slice
+	Hooks       *Hooks  `json:"-"` // optional callbacks for measuring the engine, e.g. for metrics
+}
+
+// CheckParams reports why a game with these details can't be run, or nil if it can, without running it. Run panics
+// with the same error.
+func CheckParams(p Params) error {
+	rule, err := ParseRule(p.Rule)
+	if err != nil {
+		return err
+	}
+	return checkParams(p, rule)
+}
+
+// checkParams makes sure a game can be run with its rule on its board
+func checkParams(p Params, rule Rule) error {
+	if !p.Unbounded {
+		if err := rule.Lattice.checkBoard(p.ImageWidth, p.ImageHeight); err != nil {
+			return err
+		}
+		if err := checkMargolus(rule, p.ImageWidth, p.ImageHeight); err != nil {
+			return err
+		}
+		if err := checkRange(rule, p.ImageWidth, p.ImageHeight); err != nil {
+			return err
+		}
+	}
+	for _, check := range []func(Params, Rule) error{checkUnbounded, checkNoise, checkVolume, checkColours} {
+		if err := check(p, rule); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // Run starts the processing of Game of Life. It should initialise channels and goroutines.
 func Run(p Params, events chan<- Event, keyPresses <-chan rune) {
+	RunWithCommands(p, events, keyPresses, nil)
+}
+
+// RunWithCommands is the same as Run, but also applies any Commands sent to it whilst the game is running.
+func RunWithCommands(p Params, events chan<- Event, keyPresses <-chan rune, commands <-chan Command) {
 
 	ioCommand := make(chan ioCommand)
 	ioIdle := make(chan bool)
 	filename := make(chan string)
+	outputSize := make(chan imageSize)
 	startingBoard := make(chan uint8)
 	finishedBoard := make(chan uint8)
 
@@ -21,19 +67,22 @@ func Run(p Params, events chan<- Event, keyPresses <-chan rune) {
 		command:  ioCommand,
 		idle:     ioIdle,
 		filename: filename,
+		size:     outputSize,
 		output:   finishedBoard,
 		input:    startingBoard,
 	}
 	go startIo(p, ioChannels)
 
 	distributorChannels := distributorChannels{
-		events:     events,
-		ioCommand:  ioCommand,
-		ioIdle:     ioIdle,
-		ioFilename: filename,
-		ioOutput:   finishedBoard,
-		ioInput:    startingBoard,
-		keys:       keyPresses,
+		events:       events,
+		ioCommand:    ioCommand,
+		ioIdle:       ioIdle,
+		ioFilename:   filename,
+		ioOutputSize: outputSize,
+		ioOutput:     finishedBoard,
+		ioInput:      startingBoard,
+		keys:         keyPresses,
+		commands:     commands,
 	}
 	distributor(p, distributorChannels)
 }
@@ -0,0 +1,151 @@
+package gol
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// Neighbourhood bits store the 3x3 neighbourhood of a cell, where bit (dy+1)*3+(dx+1) is set if the cell at dx, dy from
+// it is alive, so they can index the lookup table of a rule.
+const (
+	middleBit     = 1 << 4 // the bit of the cell itself
+	neighbourBits = 511 &^ middleBit
+	rightColumn   = 1<<2 | 1<<5 | 1<<8 // the bits that are replaced when sliding the neighbourhood one cell across
+)
+
+// henselLetters are the letters of the arrangements of up to 4 alive neighbours in Hensel notation, in order.
+// An arrangement of 5 to 8 alive neighbours has the letter of the arrangement of its 3 to 0 dead neighbours.
+var henselLetters = [5]string{"", "ce", "ceaikn", "ceaiknjqry", "ceaiknjqrytwz"}
+
+// henselArrangements holds one arrangement as neighbourhood bits for each letter in henselLetters, e.g. 2a is 3, the
+// top left and top middle cells. The others with the same letter are turns and reflections of it.
+var henselArrangements = [5][]int{
+	{0},
+	{1, 2},
+	{5, 10, 3, 40, 33, 68},
+	{69, 42, 11, 7, 98, 13, 14, 70, 41, 97},
+	{325, 170, 15, 45, 99, 71, 106, 102, 43, 101, 105, 78, 108},
+}
+
+// symmetries returns an arrangement turned and reflected in all 8 ways, some of which may be the same
+func symmetries(arrangement int) [8]int {
+	var turned [8]int
+	for bit := uint(0); bit < 9; bit++ {
+		if arrangement&(1<<bit) == 0 {
+			continue
+		}
+		dx, dy := int(bit%3)-1, int(bit/3)-1
+		for s, cell := range [8][2]int{{dx, dy}, {-dy, dx}, {-dx, -dy}, {dy, -dx}, {-dx, dy}, {dx, -dy}, {dy, dx}, {-dy, -dx}} {
+			turned[s] |= 1 << uint((cell[1]+1)*3+cell[0]+1)
+		}
+	}
+	return turned
+}
+
+// henselLetter returns the number of alive cells in an arrangement of neighbours, without the middle bit, and its letter, which is 0 for
+// 0 and 8 neighbours as they only have one arrangement
+func henselLetter(arrangement int) (int, byte) {
+	n := bits.OnesCount(uint(arrangement))
+	k, representative := n, arrangement
+	if n > 4 { // the same letter as the dead neighbours
+		k, representative = 8-n, neighbourBits&^arrangement
+	}
+	if k == 0 {
+		return n, 0
+	}
+	for i, other := range henselArrangements[k] {
+		for _, turned := range symmetries(other) {
+			if turned == representative {
+				return n, henselLetters[k][i]
+			}
+		}
+	}
+	return n, 0
+}
+
+// parseArrangements sets arrangements[bits] for every arrangement of alive neighbours given in Hensel notation, where
+// a number on its own is every arrangement of that many, letters after it are only those arrangements, and letters
+// after a - are every arrangement apart from those, e.g. "2-a3c" is every arrangement of 2 apart from 2a, and 3c
+func parseArrangements(digits string, arrangements *[512]bool) error {
+	digits = strings.ToLower(digits)
+	for i := 0; i < len(digits); {
+		if digits[i] < '0' || digits[i] > '8' {
+			return fmt.Errorf("%q is not a number of neighbours", digits[i])
+		}
+		n := int(digits[i] - '0')
+		i++
+		exclude := i < len(digits) && digits[i] == '-'
+		if exclude {
+			i++
+		}
+		start := i
+		for i < len(digits) && digits[i] >= 'a' && digits[i] <= 'z' {
+			if !strings.ContainsRune(henselLetters[minInt(n, 8-n)], rune(digits[i])) {
+				return fmt.Errorf("%c is not an arrangement of %d neighbours", digits[i], n)
+			}
+			i++
+		}
+		letters := digits[start:i]
+		if exclude && letters == "" {
+			return fmt.Errorf("%d- should be followed by the arrangements to leave out", n)
+		}
+		for arrangement := 0; arrangement < 512; arrangement++ {
+			if arrangement&middleBit != 0 {
+				continue
+			}
+			count, letter := henselLetter(arrangement)
+			if count != n {
+				continue
+			}
+			if letters == "" || strings.IndexByte(letters, letter) >= 0 != exclude {
+				arrangements[arrangement] = true
+			}
+		}
+	}
+	return nil
+}
+
+// henselString writes the arrangements that are set in Hensel notation, using whichever of the letters included
+// or the letters left out is shorter for each number of neighbours, e.g. "2-a3c"
+func henselString(arrangements *[512]bool) string {
+	var b strings.Builder
+	for n := 0; n <= 8; n++ {
+		set := make(map[byte]bool)
+		some, all := false, true
+		for arrangement := 0; arrangement < 512; arrangement++ {
+			if arrangement&middleBit != 0 {
+				continue
+			}
+			count, letter := henselLetter(arrangement)
+			if count != n {
+				continue
+			}
+			set[letter] = set[letter] || arrangements[arrangement]
+			some = some || arrangements[arrangement]
+			all = all && arrangements[arrangement]
+		}
+		if !some {
+			continue
+		}
+		b.WriteString(strconv.Itoa(n))
+		if all {
+			continue
+		}
+		var included, excluded strings.Builder
+		for _, letter := range []byte(henselLetters[minInt(n, 8-n)]) {
+			if set[letter] {
+				included.WriteByte(letter)
+			} else {
+				excluded.WriteByte(letter)
+			}
+		}
+		if included.Len() <= excluded.Len() {
+			b.WriteString(included.String())
+		} else {
+			b.WriteString("-" + excluded.String())
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,32 @@
+package gol
+
+import "time"
+
+// Hooks are called by the engine as it runs, so it can be measured without knowing how. Any of them may be nil.
+// They are called from the workers at the same time, so they must be safe for concurrent use.
+type Hooks struct {
+	TurnComplete   func(completedTurns int, duration time.Duration) // after each turn, with how long the turn took
+	WorkerComplete func(worker int, duration time.Duration)         // after each worker has advanced its section of a turn
+	ImageOutput    func(duration time.Duration)                     // after each image of the board has been written
+}
+
+// turnComplete calls the TurnComplete hook, if there is one
+func (hooks *Hooks) turnComplete(completedTurns int, duration time.Duration) {
+	if hooks != nil && hooks.TurnComplete != nil {
+		hooks.TurnComplete(completedTurns, duration)
+	}
+}
+
+// workerComplete calls the WorkerComplete hook, if there is one
+func (hooks *Hooks) workerComplete(worker int, duration time.Duration) {
+	if hooks != nil && hooks.WorkerComplete != nil {
+		hooks.WorkerComplete(worker, duration)
+	}
+}
+
+// imageOutput calls the ImageOutput hook, if there is one
+func (hooks *Hooks) imageOutput(duration time.Duration) {
+	if hooks != nil && hooks.ImageOutput != nil {
+		hooks.ImageOutput(duration)
+	}
+}
@@ -14,6 +14,7 @@ type ioChannels struct {
 	idle    chan<- bool
 
 	filename <-chan string
+	size     <-chan imageSize
 	output   <-chan uint8
 	input    chan<- uint8
 }
@@ -24,6 +25,12 @@ type ioState struct {
 	channels ioChannels
 }
 
+// imageSize is the width and height of an image being output, which is sent after its filename
+type imageSize struct {
+	width  int
+	height int
+}
+
 // ioCommand allows requesting behaviour from the io (pgm) goroutine.
 type ioCommand uint8
 
@@ -44,6 +51,7 @@ func (io *ioState) writePgmImage() {
 
 	// Request a filename from the distributor.
 	filename := <-io.channels.filename
+	size := <-io.channels.size
 
 	file, ioError := os.Create("out/" + filename + ".pgm")
 	util.Check(ioError)
@@ -51,20 +59,20 @@ func (io *ioState) writePgmImage() {
 
 	_, _ = file.WriteString("P5\n")
 	//_, _ = file.WriteString("# PGM file writer by pnmmodules (https://github.com/owainkenwayucl/pnmmodules).\n")
-	_, _ = file.WriteString(strconv.Itoa(io.params.ImageWidth))
+	_, _ = file.WriteString(strconv.Itoa(size.width))
 	_, _ = file.WriteString(" ")
-	_, _ = file.WriteString(strconv.Itoa(io.params.ImageHeight))
+	_, _ = file.WriteString(strconv.Itoa(size.height))
 	_, _ = file.WriteString("\n")
 	_, _ = file.WriteString(strconv.Itoa(255))
 	_, _ = file.WriteString("\n")
 
-	world := make([][]byte, io.params.ImageHeight)
+	world := make([][]byte, size.height)
 	for i := range world {
-		world[i] = make([]byte, io.params.ImageWidth)
+		world[i] = make([]byte, size.width)
 	}
 
-	for y := 0; y < io.params.ImageHeight; y++ {
-		for x := 0; x < io.params.ImageWidth; x++ {
+	for y := 0; y < size.height; y++ {
+		for x := 0; x < size.width; x++ {
 			val := <-io.channels.output
 			//if val != 0 {
 			//	fmt.Println(x, y)
@@ -73,8 +81,8 @@ func (io *ioState) writePgmImage() {
 		}
 	}
 
-	for y := 0; y < io.params.ImageHeight; y++ {
-		for x := 0; x < io.params.ImageWidth; x++ {
+	for y := 0; y < size.height; y++ {
+		for x := 0; x < size.width; x++ {
 			_, ioError = file.Write([]byte{world[y][x]})
 			util.Check(ioError)
 		}
@@ -95,9 +103,9 @@ func (io *ioState) readPgmImage() {
 	data, ioError := ioutil.ReadFile("images/" + filename + ".pgm")
 	util.Check(ioError)
 
-	fields := strings.Fields(string(data))
+	fields, image := splitPgm(data)
 
-	if fields[0] != "P5" {
+	if len(fields) < 4 || fields[0] != "P5" {
 		panic("Not a pgm file")
 	}
 
@@ -107,7 +115,7 @@ func (io *ioState) readPgmImage() {
 	}
 
 	height, _ := strconv.Atoi(fields[2])
-	if height != io.params.ImageHeight {
+	if height != io.params.ImageHeight*maxInt(io.params.Depth, 1) { // a 3D board has its slices one under another
 		panic("Incorrect height")
 	}
 
@@ -116,15 +124,42 @@ func (io *ioState) readPgmImage() {
 		panic("Incorrect maxval/bit depth")
 	}
 
-	image := []byte(fields[4])
+	if len(image) < width*height {
+		panic("Not enough pixels")
+	}
 
-	for _, b := range image {
+	for _, b := range image[:width*height] {
 		io.channels.input <- b
 	}
 
 	fmt.Println("File", filename, "input done!")
 }
 
+// splitPgm splits a pgm file into the four fields of its header and the pixels after it. The pixels are taken as
+// they are rather than split on whitespace, as grey levels such as the dying states of Generations rules can be
+// whitespace characters.
+func splitPgm(data []byte) ([]string, []byte) {
+	var fields []string
+	i := 0
+	for len(fields) < 4 && i < len(data) {
+		switch {
+		case data[i] == '#': // a comment, up to the end of the line
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+		case strings.ContainsRune(" \t\r\n", rune(data[i])):
+			i++
+		default:
+			start := i
+			for i < len(data) && !strings.ContainsRune(" \t\r\n", rune(data[i])) {
+				i++
+			}
+			fields = append(fields, string(data[start:i]))
+		}
+	}
+	return fields, data[minInt(i+1, len(data)):] // after the one whitespace character that ends the header
+}
+
 // startIo should be the entrypoint of the io goroutine.
 func startIo(p Params, c ioChannels) {
 	io := ioState{
@@ -0,0 +1,141 @@
+package gol
+
+import (
+	"fmt"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// summedArea is a summed-area table of the alive cells in a rectangle, so the alive cells in any rectangle within it
+// can be counted with four lookups however far the rule reaches. A table of a whole board wraps around its edges.
+type summedArea struct {
+	sums   []int32 // sums[j*stride+i] is the number of alive cells above and to the left of cell i, j of the table
+	stride int
+	x      int // position of the top left cell of the table, which can be off the board
+	y      int
+	width  int // the size of the board the table wraps around, or 0 if it doesn't
+	height int
+}
+
+// newSummedArea builds the table for a width by height rectangle with its top left cell at x, y
+func newSummedArea(x int, y int, width int, height int, alive func(x int, y int) bool) *summedArea {
+	table := &summedArea{sums: make([]int32, (width+1)*(height+1)), stride: width + 1, x: x, y: y}
+	for j := 0; j < height; j++ {
+		var row int32 // alive cells so far in this row
+		for i := 0; i < width; i++ {
+			if alive(x+i, y+j) {
+				row++
+			}
+			table.sums[(j+1)*table.stride+i+1] = table.sums[j*table.stride+i+1] + row
+		}
+	}
+	return table
+}
+
+// count returns the number of alive cells from x0, y0 up to but not including x1, y1. On a table that wraps around,
+// the rectangle can start anywhere but be no larger than the board, and is counted as up to four rectangles on it.
+func (table *summedArea) count(x0 int, y0 int, x1 int, y1 int) int {
+	if table.width == 0 {
+		return table.sum(x0-table.x, y0-table.y, x1-table.x, y1-table.y)
+	}
+	across, down := x1-x0, y1-y0
+	x0, y0 = wrap(x0, table.width), wrap(y0, table.height)
+	x1, y1 = x0+across, y0+down
+	count := 0
+	for _, xs := range [2][2]int{{x0, minInt(x1, table.width)}, {0, x1 - table.width}} {
+		for _, ys := range [2][2]int{{y0, minInt(y1, table.height)}, {0, y1 - table.height}} {
+			if xs[1] > xs[0] && ys[1] > ys[0] {
+				count += table.sum(xs[0], ys[0], xs[1], ys[1])
+			}
+		}
+	}
+	return count
+}
+
+// sum returns the number of alive cells in a rectangle of the table, from the top left of the table
+func (table *summedArea) sum(x0 int, y0 int, x1 int, y1 int) int {
+	return int(table.sums[y1*table.stride+x1] - table.sums[y0*table.stride+x1] - table.sums[y1*table.stride+x0] + table.sums[y0*table.stride+x0])
+}
+
+// neighbours counts the alive cells in the neighbourhood of a cell following the rule. A Moore neighbourhood is one
+// rectangle, whilst a von Neumann neighbourhood is counted one row at a time.
+func (table *summedArea) neighbours(rule Rule, x int, y int, alive bool) int {
+	r := rule.Range
+	count := 0
+	if rule.Neighbourhood == VonNeumann {
+		for dy := -r; dy <= r; dy++ {
+			across := r - absInt(dy)
+			count += table.count(x-across, y+dy, x+across+1, y+dy+1)
+		}
+	} else {
+		count = table.count(x-r, y-r, x+r+1, y+r+1)
+	}
+	if alive && !rule.Middle { // the cell was counted as part of its own neighbourhood
+		count--
+	}
+	return count
+}
+
+// AdvanceLargerSection is AdvanceSection for extended rules, counting the neighbours of each cell from the summedArea
+// of the whole board built for the turn
+func (game *Game) AdvanceLargerSection(startX int, endX int, startY int, endY int) bool {
+	var flipped []util.Cell // only used when batching, otherwise each flip is sent straight away
+	table := game.area
+	changed := false
+	for j := startY; j < endY; j++ {
+		for i := startX; i < endX; i++ {
+			value := game.current.Get(i, j)
+			newValue := game.noisy(i, j, value, game.rule.next(value, table.neighbours(game.rule, i, j, value == 255)))
+			game.advanced.Set(i, j, newValue)
+			if newValue != value {
+				changed = true
+				game.cellChanged(util.Cell{X: i, Y: j}, newValue, &flipped)
+			}
+		}
+	}
+	game.SendFlips(flipped)
+	return changed
+}
+
+// summedArea builds the table for the whole board, wrapping around its edges. It is built once a turn, before the
+// workers start, and shared between them, as the current board only changes between turns.
+func (board *Board) summedArea() *summedArea {
+	table := newSummedArea(0, 0, board.width, board.height, func(x int, y int) bool {
+		return board.Get(x, y) == 255
+	})
+	table.width, table.height = board.width, board.height
+	return table
+}
+
+// countsArea checks if the rule is advanced by AdvanceLargerSection, which needs a summedArea of the board
+func (rule Rule) countsArea() bool {
+	return rule.transitions == nil && rule.Margolus == nil && rule.Lattice == Square && rule.extended()
+}
+
+// checkRange makes sure the neighbourhood of an extended rule fits on a board that wraps around, so no cell is
+// counted twice
+func checkRange(rule Rule, width int, height int) error {
+	if size := 2*rule.Range + 1; rule.extended() && (size > width || size > height) {
+		return fmt.Errorf("rule %v counts %d cells across, so it needs a board of at least %dx%d, not %dx%d", rule, size, size, size, width, height)
+	}
+	return nil
+}
+
+// wrap returns the position on a board of the given size for a position that may be off the board by any amount
+func wrap(position int, size int) int {
+	return (position%size + size) % size
+}
+
+// absInt returns the absolute value of an int
+func absInt(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+// reach returns how many tiles or chunks of the given size away a cell can be and still be in the neighbourhood of a
+// cell in the middle one
+func (rule Rule) reach(size int) int {
+	return (rule.Range + size - 1) / size
+}
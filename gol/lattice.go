@@ -0,0 +1,149 @@
+package gol
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// Lattice is the shape of the cells, which are all stored on the same rectangular board
+type Lattice int
+
+const (
+	Square     Lattice = iota
+	Hexagonal          // rows are offset by half a cell, with odd rows shifted right, so each cell has 6 neighbours
+	Triangular         // cells point up when x+y is even and down when it is odd, and touch 12 neighbours along an edge or at a corner
+)
+
+// hexagonal neighbours of cells in even and odd rows, as dx, dy
+var (
+	evenRowNeighbours = [][2]int{{-1, -1}, {0, -1}, {-1, 0}, {1, 0}, {-1, 1}, {0, 1}}
+	oddRowNeighbours  = [][2]int{{0, -1}, {1, -1}, {-1, 0}, {1, 0}, {0, 1}, {1, 1}}
+)
+
+// triangular neighbours of cells pointing up and down, as dx, dy. A cell pointing up touches 3 cells in the row
+// above at its top corner and 5 below along its bottom edge.
+var (
+	upNeighbours = [][2]int{
+		{-1, -1}, {0, -1}, {1, -1},
+		{-2, 0}, {-1, 0}, {1, 0}, {2, 0},
+		{-2, 1}, {-1, 1}, {0, 1}, {1, 1}, {2, 1},
+	}
+	downNeighbours = [][2]int{
+		{-2, -1}, {-1, -1}, {0, -1}, {1, -1}, {2, -1},
+		{-2, 0}, {-1, 0}, {1, 0}, {2, 0},
+		{-1, 1}, {0, 1}, {1, 1},
+	}
+)
+
+// neighbours returns where the neighbours of a cell are on a lattice other than Square, which depends on the cell.
+// x and y can be negative in an unbounded game.
+func (lattice Lattice) neighbours(x int, y int) [][2]int {
+	if lattice == Hexagonal {
+		if y&1 == 0 {
+			return evenRowNeighbours
+		}
+		return oddRowNeighbours
+	}
+	if (x+y)&1 == 0 {
+		return upNeighbours
+	}
+	return downNeighbours
+}
+
+// size returns the number of neighbours each cell has
+func (lattice Lattice) size() int {
+	switch lattice {
+	case Hexagonal:
+		return 6
+	case Triangular:
+		return 12
+	default:
+		return 8
+	}
+}
+
+// suffix returns the letter at the end of rulestrings for the lattice
+func (lattice Lattice) suffix() string {
+	switch lattice {
+	case Hexagonal:
+		return "H"
+	case Triangular:
+		return "L"
+	default:
+		return ""
+	}
+}
+
+// checkBoard makes sure the lattice wraps around a board of the given size without breaking the pattern of rows or
+// triangles, which needs an even height for hexagons and an even width and height for triangles
+func (lattice Lattice) checkBoard(width int, height int) error {
+	switch {
+	case lattice == Hexagonal && height%2 != 0:
+		return fmt.Errorf("a hexagonal board needs an even height to wrap around, not %d", height)
+	case lattice == Triangular && (width%2 != 0 || height%2 != 0):
+		return fmt.Errorf("a triangular board needs an even width and height to wrap around, not %dx%d", width, height)
+	}
+	return nil
+}
+
+// parseLatticeCounts sets counts[n] for every number of neighbours n, which are single digits unless they are
+// separated by commas, e.g. "345" or "4,10,12" as triangular cells can have up to 12
+func parseLatticeCounts(text string, counts []bool) error {
+	numbers := strings.Split(text, ",")
+	if !strings.Contains(text, ",") {
+		numbers = strings.Split(text, "")
+	}
+	for _, number := range numbers {
+		n, err := strconv.Atoi(number)
+		if err != nil || n < 0 || n >= len(counts) {
+			return fmt.Errorf("%q is not a number of neighbours between 0 and %d", number, len(counts)-1)
+		}
+		counts[n] = true
+	}
+	return nil
+}
+
+// latticeCounts writes the numbers of neighbours that are set, separated by commas if any of them are more than 9
+func latticeCounts(counts []bool) string {
+	var numbers []string
+	separator := ""
+	for n, set := range counts {
+		if set {
+			numbers = append(numbers, strconv.Itoa(n))
+		}
+		if set && n > 9 {
+			separator = ","
+		}
+	}
+	return strings.Join(numbers, separator)
+}
+
+// AdvanceLatticeSection is AdvanceSection for hexagonal and triangular rules, counting the neighbours of each cell
+// from the lattice and accounting for wrap around
+func (game *Game) AdvanceLatticeSection(startX int, endX int, startY int, endY int) bool {
+	var flipped []util.Cell // only used when batching, otherwise each flip is sent straight away
+	board := game.current
+	changed := false
+	for j := startY; j < endY; j++ {
+		for i := startX; i < endX; i++ {
+			aliveNeighbours := 0
+			for _, offset := range game.rule.Lattice.neighbours(i, j) {
+				if board.Alive(wrap(i+offset[0], board.width), wrap(j+offset[1], board.height), false) {
+					aliveNeighbours++
+				}
+			}
+			value := board.Get(i, j)
+			newValue := game.noisy(i, j, value, game.rule.next(value, aliveNeighbours))
+			game.advanced.Set(i, j, newValue)
+			if newValue != value {
+				changed = true
+				game.cellChanged(util.Cell{X: i, Y: j}, newValue, &flipped)
+			}
+		}
+	}
+	game.SendFlips(flipped)
+	return changed
+}
@@ -0,0 +1,89 @@
+package gol
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// margolusCells are the cells of a 2x2 block in the order of their bits in a Margolus rule, as dx, dy from its top left
+var margolusCells = [4][2]int{{0, 0}, {1, 0}, {0, 1}, {1, 1}}
+
+// parseMargolus reads a Margolus rule in Golly's notation, e.g. "M0,8,4,3,2,5,9,7,1,6,10,11,12,13,14,15" for the
+// billiard-ball machine: the block each of the 16 blocks of 2x2 cells becomes, where the alive cells of a block are
+// its bits, 1 for the top left, 2 for the top right, 4 for the bottom left and 8 for the bottom right.
+func parseMargolus(rulestring string) (Rule, error) {
+	rule := Rule{States: 2, Range: 1, Margolus: make([]int, 16)}
+	blocks := strings.Split(rulestring[1:], ",")
+	if len(blocks) != 16 {
+		return rule, fmt.Errorf("Margolus rule %q should have what each of the 16 blocks becomes", rulestring)
+	}
+	for i, block := range blocks {
+		n, err := strconv.Atoi(strings.TrimSpace(block))
+		if err != nil || n < 0 || n > 15 {
+			return rule, fmt.Errorf("Margolus rule %q: %q is not a block between 0 and 15", rulestring, block)
+		}
+		rule.Margolus[i] = n
+	}
+	rule.Birth = []bool{rule.Margolus[0] != 0} // so empty tiles aren't skipped if empty blocks fill up, and unbounded boards are refused
+	return rule, nil
+}
+
+// margolusString writes a Margolus rule in Golly's notation
+func margolusString(rule Rule) string {
+	blocks := make([]string, len(rule.Margolus))
+	for i, block := range rule.Margolus {
+		blocks[i] = strconv.Itoa(block)
+	}
+	return "M" + strings.Join(blocks, ",")
+}
+
+// checkMargolus makes sure a board can be split into 2x2 blocks both ways round, which needs an even width and height
+func checkMargolus(rule Rule, width int, height int) error {
+	if rule.Margolus != nil && (width%2 != 0 || height%2 != 0) {
+		return fmt.Errorf("a Margolus rule needs an even width and height to wrap around, not %dx%d", width, height)
+	}
+	return nil
+}
+
+// margolusNext returns the value of a cell after a turn following a Margolus rule. The blocks start on even cells on
+// even turns and on odd cells on odd turns, so x and y only need to have the same parity as the position of the cell.
+// alive is given the position of each cell in the block the cell is in.
+func (game *Game) margolusNext(x int, y int, value uint8, alive func(x int, y int) bool) uint8 {
+	offset := game.completedTurns % 2
+	blockX, blockY := (x-offset)&^1+offset, (y-offset)&^1+offset // rounding down, even off the board when unbounded
+	block := 0
+	for bit, cell := range margolusCells {
+		if alive(blockX+cell[0], blockY+cell[1]) {
+			block |= 1 << uint(bit)
+		}
+	}
+	bit := uint((y-blockY)*2 + x - blockX)
+	return game.rule.after(value, game.rule.Margolus[block]&(1<<bit) != 0)
+}
+
+// AdvanceMargolusSection is AdvanceSection for Margolus rules, working out each cell from the block it is in,
+// accounting for wrap around. Blocks across the edge of a section are worked out by both sections, which each keep
+// their own cells of it.
+func (game *Game) AdvanceMargolusSection(startX int, endX int, startY int, endY int) bool {
+	var flipped []util.Cell // only used when batching, otherwise each flip is sent straight away
+	board := game.current
+	changed := false
+	for j := startY; j < endY; j++ {
+		for i := startX; i < endX; i++ {
+			value := board.Get(i, j)
+			newValue := game.noisy(i, j, value, game.margolusNext(i, j, value, func(x int, y int) bool {
+				return board.Alive(wrap(x, board.width), wrap(y, board.height), false)
+			}))
+			game.advanced.Set(i, j, newValue)
+			if newValue != value {
+				changed = true
+				game.cellChanged(util.Cell{X: i, Y: j}, newValue, &flipped)
+			}
+		}
+	}
+	game.SendFlips(flipped)
+	return changed
+}
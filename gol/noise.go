@@ -0,0 +1,60 @@
+package gol
+
+import (
+	"errors"
+	"fmt"
+)
+
+// checkNoise makes sure the noise is a probability and the game can have it. Noise can make any cell alive, so it
+// can't be used on an unbounded board, rule tables have no alive and dead for it to swap, and it only hashes the x and
+// y of a cell, which isn't enough for a 3D board.
+func checkNoise(p Params, rule Rule) error {
+	switch {
+	case p.Noise == 0:
+		return nil
+	case p.Noise < 0 || p.Noise > 1:
+		return fmt.Errorf("noise should be a probability between 0 and 1, not %v", p.Noise)
+	case p.Unbounded:
+		return errors.New("noise can't be used on an unbounded board")
+	case rule.transitions != nil:
+		return errors.New("noise can't be used with a rule table")
+	case rule.Voxels:
+		return errors.New("noise can't be used with a 3D rule")
+	}
+	return nil
+}
+
+// noisy returns the value of a cell after a turn, given its value now and its value after the turn following the rule.
+// With probability Noise the cell does the opposite of what the rule says: a cell the rule has alive isn't, and a dead
+// or alive cell the rule doesn't have alive is, so births and deaths happen that shouldn't and don't that should.
+// Dying cells can't be born, so they are left as they are.
+func (game *Game) noisy(x int, y int, value uint8, newValue uint8) uint8 {
+	if game.noise == 0 || !game.noiseHits(x, y) {
+		return newValue
+	}
+	if newValue == 255 {
+		return game.rule.after(value, false)
+	}
+	if value == 0 || value == 255 {
+		return 255
+	}
+	return newValue
+}
+
+// noiseHits decides whether noise changes a cell on the turn being worked out. It hashes the seed, the turn and the
+// position of the cell instead of drawing from a generator, so the same cells are changed whichever worker advances
+// them and however many workers there are. Each is mixed in on its own, so no two keys can cancel each other out the
+// way the seed and turn would if they were combined first.
+func (game *Game) noiseHits(x int, y int) bool {
+	bits := splitMix(splitMix(splitMix(splitMix(uint64(game.seed))^uint64(game.completedTurns))^uint64(x)) ^ uint64(y))
+	return float64(bits>>11)/(1<<53) < game.noise // the top 53 bits, as many as a float64 holds exactly
+}
+
+// splitMix scrambles the bits of a number, as in the SplitMix64 generator, so numbers that are close together give
+// unrelated results
+func splitMix(z uint64) uint64 {
+	z += 0x9e3779b97f4a7c15
+	z = (z ^ z>>30) * 0xbf58476d1ce4e5b9
+	z = (z ^ z>>27) * 0x94d049bb133111eb
+	return z ^ z>>31
+}
@@ -0,0 +1,281 @@
+package gol
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// Pattern stores the alive cells of a small pattern, relative to its top left corner
+type Pattern struct {
+	Name   string
+	Width  int
+	Height int
+	Cells  []util.Cell
+	States []int // the state of each of the Cells for rules with more than two states, or nil if they are all alive
+}
+
+// Rotation is a clockwise rotation of a pattern in steps of 90 degrees
+type Rotation int
+
+const (
+	Rotate0 Rotation = iota
+	Rotate90
+	Rotate180
+	Rotate270
+)
+
+// Rotate returns a copy of the pattern turned clockwise by the given rotation
+func (pattern Pattern) Rotate(rotation Rotation) Pattern {
+	rotated := pattern
+	rotated.Cells = make([]util.Cell, len(pattern.Cells))
+	copy(rotated.Cells, pattern.Cells)
+	for r := 0; r < int(rotation)%4; r++ { // apply 90 degree turns one at a time
+		for i, cell := range rotated.Cells {
+			rotated.Cells[i] = util.Cell{X: rotated.Height - 1 - cell.Y, Y: cell.X}
+		}
+		rotated.Width, rotated.Height = rotated.Height, rotated.Width
+	}
+	return rotated
+}
+
+// ParseRLE reads a pattern in run length encoded format, e.g. "x = 3, y = 3\nbob$2bo$3o!". Multi-state patterns
+// give states 1 to 24 as A to X, and higher states with a letter from p to y in front, e.g. "pA" for 25.
+func ParseRLE(name string, data string) (Pattern, error) {
+	pattern := Pattern{Name: name}
+	header := false
+	x, y := 0, 0
+	multiState := false
+	high := 0 // 24 for each letter after o in front of the current state, e.g. 24 for "pA"
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#") { // comments, but "#N" holds the name of the pattern
+			if strings.HasPrefix(line, "#N") {
+				pattern.Name = strings.TrimSpace(line[2:])
+			}
+			continue
+		}
+		if !header { // the first line that isn't a comment gives the size of the pattern
+			for _, field := range strings.Split(line, ",") {
+				parts := strings.SplitN(field, "=", 2)
+				if len(parts) != 2 {
+					return pattern, fmt.Errorf("%v: malformed RLE header %q", name, line)
+				}
+				value, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+				switch strings.TrimSpace(parts[0]) {
+				case "x":
+					pattern.Width = value
+				case "y":
+					pattern.Height = value
+				default:
+					continue // e.g. the rule, which we ignore here
+				}
+				if err != nil {
+					return pattern, fmt.Errorf("%v: malformed RLE header %q", name, line)
+				}
+			}
+			header = true
+			continue
+		}
+		count := 0
+		for _, char := range line {
+			switch {
+			case char >= '0' && char <= '9':
+				count = count*10 + int(char-'0')
+				continue
+			case char == '!': // end of pattern
+				return pattern.withStates(multiState), pattern.checkBounds()
+			case char >= 'p' && char <= 'y':
+				high = int(char-'o') * 24
+				continue
+			}
+			if count == 0 {
+				count = 1
+			}
+			switch char {
+			case '$': // end of one or more rows
+				y += count
+				x = 0
+			case 'b', '.': // dead cells
+				x += count
+			default: // anything else is some kind of alive cell
+				state := 1
+				if char >= 'A' && char <= 'X' {
+					state = high + int(char-'A') + 1
+				}
+				multiState = multiState || state != 1
+				for i := 0; i < count; i++ {
+					pattern.Cells = append(pattern.Cells, util.Cell{X: x, Y: y})
+					pattern.States = append(pattern.States, state)
+					x++
+				}
+			}
+			count, high = 0, 0
+		}
+	}
+	if !header {
+		return pattern, fmt.Errorf("%v: missing RLE header", name)
+	}
+	return pattern.withStates(multiState), pattern.checkBounds()
+}
+
+// withStates returns the pattern with its States left out if every cell is alive
+func (pattern Pattern) withStates(multiState bool) Pattern {
+	if !multiState {
+		pattern.States = nil
+	}
+	return pattern
+}
+
+// ParseCells reads a pattern in plaintext format, where '.' is a dead cell and 'O' is an alive cell
+func ParseCells(name string, data string) (Pattern, error) {
+	pattern := Pattern{Name: name}
+	y := 0
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.HasPrefix(line, "!") { // comments, but "!Name:" holds the name of the pattern
+			if strings.HasPrefix(line, "!Name:") {
+				pattern.Name = strings.TrimSpace(line[len("!Name:"):])
+			}
+			continue
+		}
+		for x, char := range line {
+			if char == 'O' || char == '*' {
+				pattern.Cells = append(pattern.Cells, util.Cell{X: x, Y: y})
+			}
+			if x+1 > pattern.Width {
+				pattern.Width = x + 1
+			}
+		}
+		if strings.TrimSpace(line) != "" { // trailing empty lines don't count towards the height
+			pattern.Height = y + 1
+		}
+		y++
+	}
+	return pattern, pattern.checkBounds()
+}
+
+// checkBounds makes sure the pattern is not empty and all of its cells lie within its width and height
+func (pattern Pattern) checkBounds() error {
+	if len(pattern.Cells) == 0 {
+		return fmt.Errorf("%v: pattern has no alive cells", pattern.Name)
+	}
+	for _, cell := range pattern.Cells {
+		if cell.X >= pattern.Width || cell.Y >= pattern.Height {
+			return fmt.Errorf("%v: cell (%d, %d) is outside the %dx%d pattern", pattern.Name, cell.X, cell.Y, pattern.Width, pattern.Height)
+		}
+	}
+	return nil
+}
+
+// LoadPattern reads an .rle or .cells file, using the file name as the pattern name unless the file gives one
+func LoadPattern(path string) (Pattern, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Pattern{}, err
+	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".rle":
+		return ParseRLE(name, string(data))
+	case ".cells":
+		return ParseCells(name, string(data))
+	default:
+		return Pattern{}, errors.New(path + ": unknown pattern format")
+	}
+}
+
+// LoadPatternLibrary reads every .rle and .cells file in a directory, sorted by file name
+func LoadPatternLibrary(dir string) ([]Pattern, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name() < files[j].Name() })
+	var patterns []Pattern
+	for _, file := range files {
+		switch strings.ToLower(filepath.Ext(file.Name())) {
+		case ".rle", ".cells":
+			pattern, err := LoadPattern(filepath.Join(dir, file.Name()))
+			if err != nil {
+				return nil, err
+			}
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns, nil
+}
+
+// Stamp places a pattern onto the current board with its top left corner at x, y, wrapping around the edges
+// unless the game is unbounded. The cells of a multi-state pattern are stored as the values of their states, or for
+// rules with colours, as alive with the colour of their state, from 1 for the first colour.
+// On a 3D board, the pattern is placed on the slice being shown.
+// Every cell inside the pattern's bounding box is overwritten, and the cells that change are sent as flipped.
+// It must only be called between turns, as the workers read the current board without locking.
+func (game *Game) Stamp(pattern Pattern, x int, y int, rotation Rotation) {
+	pattern = pattern.Rotate(rotation)
+	values := make(map[util.Cell]uint8, len(pattern.Cells))
+	for i, cell := range pattern.Cells {
+		values[cell] = 255
+		switch {
+		case pattern.States != nil && game.rule.Colours > 0: // states are colours, with the ones the rule doesn't have as its last
+			values[cell] = colourShade(minInt(pattern.States[i], game.rule.Colours)-1, game.rule.Colours)
+		case pattern.States != nil: // states the rule doesn't have are stamped as its last state
+			values[cell] = game.rule.shade(minInt(pattern.States[i], game.rule.States-1))
+		}
+	}
+	game.raceMutex.Lock() // make sure the board isn't being counted or output whilst we change it
+	defer game.raceMutex.Unlock()
+	var flipped []util.Cell
+	for j := 0; j < pattern.Height; j++ {
+		for i := 0; i < pattern.Width; i++ {
+			value := values[util.Cell{X: i, Y: j}]
+			if game.volume != nil { // on to the slice being shown
+				volume := game.volume
+				cellX, cellY := wrap(x+i, volume.width), wrap(y+j, volume.height)
+				if old := volume.Get(cellX, game.slice*volume.height+cellY); old != value {
+					volume.Set(cellX, game.slice*volume.height+cellY, value)
+					game.stamped(util.Cell{X: cellX, Y: cellY}, old, value, &flipped)
+				}
+				continue
+			}
+			if game.universe != nil {
+				if old := game.universe.Get(x+i, y+j); old != value {
+					game.universe.Set(x+i, y+j, value)
+					game.stamped(util.Cell{X: x + i, Y: y + j}, old, value, &flipped)
+				}
+				continue
+			}
+			cellX := ((x+i)%game.current.width + game.current.width) % game.current.width
+			cellY := ((y+j)%game.current.height + game.current.height) % game.current.height
+			if old := game.current.Shade(cellX, cellY); old != value {
+				game.current.SetShade(cellX, cellY, value)
+				game.stamped(util.Cell{X: cellX, Y: cellY}, old, value, &flipped)
+				tile := game.current.tileAt(cellX, cellY)
+				game.tileChanged[tile] = true // so the tiles around it are advanced on the next turn
+				if old == 0 {
+					game.current.tileAlive[tile]++
+				} else if value == 0 {
+					game.current.tileAlive[tile]--
+				}
+			}
+		}
+	}
+	game.SendFlips(flipped)
+}
+
+// stamped sends the event for a cell changed by a stamp. A dying cell that is stamped dead doesn't flip, as dying
+// cells aren't alive, so it is only sent for rules with more than two states or with colours.
+func (game *Game) stamped(cell util.Cell, old uint8, value uint8, flipped *[]util.Cell) {
+	if old == 255 || value == 255 || game.rule.States > 2 || game.rule.Colours > 0 {
+		game.cellChanged(cell, value, flipped)
+	}
+}
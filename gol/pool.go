@@ -0,0 +1,64 @@
+package gol
+
+import (
+	"sync"
+	"time"
+)
+
+// workerTurn is what one worker does on one turn
+type workerTurn struct {
+	work func(worker int)
+	wg   *sync.WaitGroup
+}
+
+// workerPool holds the workers that advance the board. They are started before the first turn and kept between turns,
+// each waiting for the work of the next one, so goroutines are only started again when the number of threads changes.
+type workerPool struct {
+	turns []chan workerTurn // one for each worker
+}
+
+// startWorkers replaces the workers with a pool of the given size
+func (game *Game) startWorkers(workers int) {
+	game.stopWorkers()
+	game.pool = &workerPool{turns: make([]chan workerTurn, workers)}
+	game.workerTimes = make([]time.Duration, workers)
+	for i := range game.pool.turns {
+		game.pool.turns[i] = make(chan workerTurn, 1)
+		go game.runWorker(i, game.pool.turns[i])
+	}
+}
+
+// stopWorkers ends the workers, which must have finished their turn
+func (game *Game) stopWorkers() {
+	if game.pool == nil {
+		return
+	}
+	for _, turns := range game.pool.turns {
+		close(turns)
+	}
+	game.pool = nil
+}
+
+// runWorker does the work it is given every turn until the pool is stopped, timing each turn
+func (game *Game) runWorker(worker int, turns <-chan workerTurn) {
+	for turn := range turns {
+		start := time.Now()
+		turn.work(worker)
+		duration := time.Since(start)
+		game.workerTimes[worker] = duration // each worker has its own element, so there's no race
+		game.hooks.workerComplete(worker, duration)
+		turn.wg.Done()
+	}
+}
+
+// startTurn gives every worker its work for the turn, which is called with the number of the worker. The pool is
+// replaced first if it isn't the given size, e.g. after the number of threads was changed.
+func (game *Game) startTurn(wg *sync.WaitGroup, workers int, work func(worker int)) {
+	if game.pool == nil || len(game.pool.turns) != workers {
+		game.startWorkers(workers)
+	}
+	for _, turns := range game.pool.turns {
+		wg.Add(1)
+		turns <- workerTurn{work: work, wg: wg}
+	}
+}
@@ -0,0 +1,397 @@
+package gol
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Neighbourhood is the shape of the cells around a cell that are counted by a rule
+type Neighbourhood int
+
+const (
+	Moore      Neighbourhood = iota // the square of cells within Range across and down
+	VonNeumann                      // the diamond of cells within Range steps up, down, left and right
+)
+
+// Rule decides which dead cells are born and which alive cells survive, from the alive cells around them.
+// With more than 2 States, cells that don't survive spend States-2 turns dying before they are dead. Dying cells
+// don't count as alive neighbours and can't be born again until they are dead, as in Brian's Brain.
+// Rules should be made with ParseRule, which fills in the lookup table.
+type Rule struct {
+	Birth         []bool // Birth[n] is set if a dead cell with n alive neighbours is born, in at least one arrangement of them
+	Survive       []bool // Survive[n] is set if an alive cell with n alive neighbours survives, in at least one arrangement of them
+	States        int    // 2 for life-like rules, more for Generations rules
+	Range         int    // how far the neighbourhood reaches, 1 for the 3x3 neighbourhood and more for Larger than Life
+	Neighbourhood Neighbourhood
+	Middle        bool       // whether a cell counts itself as one of its neighbours, which Larger than Life rules can choose
+	Lattice       Lattice    // the shape of the cells, Square unless the rulestring ends in H or L
+	table         []bool     // whether a cell is alive after a turn, indexed by its neighbourhood bits, unless the rule is extended
+	transitions   *ruleTable // the transitions of a rule loaded from a .rule file, which only sets States and Birth[0]
+	Voxels        bool       // whether the rule is for a 3D board, counting the 26 cells around each cell, see Params.Depth
+	Margolus      []int      // the block each 2x2 block of cells becomes, for Margolus rules, see parseMargolus
+	Colours       int        // the number of colours alive cells can be, 2 for Immigration and 4 for QuadLife, or 0
+}
+
+// Conway is the rule of Conway's Game of Life, B3/S23
+var Conway, _ = parseLifeLike("B3/S23")
+
+// ParseRule reads a rulestring in B/S notation, e.g. "B3/S23", or "B2/S/C3" for a Generations rule,
+// as well as the older S/B notation, e.g. "23/3", or "/2/3" for a Generations rule. An empty rulestring is Conway.
+// The numbers of neighbours can be followed by letters in Hensel notation for isotropic non-totalistic rules, see
+// parseArrangements, e.g. "B2-a/S12". Larger than Life rules are read in Golly's notation, see parseLarger.
+// An H at the end is a rule for hexagonal cells, e.g. "B2/S34H", and an L for triangular cells, e.g. "B4,5/S4,5,6,7L".
+// A path to a .rule file loads a rule table written for Golly, see parseRuleTable, e.g. "rules/Wireworld.rule".
+// 3D rules are read in Bays' notation, see parseVoxels, e.g. "4555", and Margolus rules in Golly's notation, see
+// parseMargolus, e.g. "M0,8,4,3,2,5,9,7,1,6,10,11,12,13,14,15". Immigration or QuadLife at the end gives alive cells
+// 2 or 4 colours, see parseColours, e.g. "QuadLife" or "B36/S23Immigration".
+func ParseRule(rulestring string) (Rule, error) {
+	if rulestring == "" {
+		return Conway, nil
+	}
+	if strings.Trim(rulestring, "0123456789,") == "" {
+		return parseVoxels(rulestring)
+	}
+	if len(rulestring) > 1 && (rulestring[0] == 'M' || rulestring[0] == 'm') && rulestring[1] >= '0' && rulestring[1] <= '9' {
+		return parseMargolus(rulestring)
+	}
+	if rule, ok, err := parseColours(rulestring); ok {
+		return rule, err
+	}
+	if strings.HasSuffix(strings.ToLower(rulestring), ".rule") {
+		return loadRuleTable(rulestring)
+	}
+	if len(rulestring) > 1 && (rulestring[0] == 'R' || rulestring[0] == 'r') && rulestring[1] >= '0' && rulestring[1] <= '9' {
+		return parseLarger(rulestring)
+	}
+	return parseLifeLike(rulestring)
+}
+
+// parseLifeLike reads a rulestring in B/S or S/B notation
+func parseLifeLike(rulestring string) (Rule, error) {
+	rule := Rule{Birth: make([]bool, 9), Survive: make([]bool, 9), States: 2, Range: 1}
+	body := rulestring
+	switch rulestring[len(rulestring)-1] {
+	case 'H', 'h':
+		rule.Lattice, body = Hexagonal, rulestring[:len(rulestring)-1]
+	case 'L', 'l':
+		rule.Lattice, body = Triangular, rulestring[:len(rulestring)-1]
+	}
+	parts := strings.Split(body, "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		return rule, fmt.Errorf("rule %q should have 2 or 3 parts separated by /", rulestring)
+	}
+	upper := strings.ToUpper(parts[0])
+	var birth, survive, states string
+	if strings.HasPrefix(upper, "B") || strings.HasPrefix(upper, "S") { // B/S notation, in either order
+		for _, part := range parts {
+			part = strings.ToUpper(part)
+			switch {
+			case strings.HasPrefix(part, "B"):
+				birth = part[1:]
+			case strings.HasPrefix(part, "S"):
+				survive = part[1:]
+			case strings.HasPrefix(part, "C") || strings.HasPrefix(part, "G"):
+				states = part[1:]
+			default:
+				return rule, fmt.Errorf("rule %q has a part %q that isn't B, S or C", rulestring, part)
+			}
+		}
+	} else { // S/B notation, with the number of states last
+		survive, birth = parts[0], parts[1]
+		if len(parts) == 3 {
+			states = parts[2]
+		}
+	}
+	if states != "" {
+		n, err := strconv.Atoi(states)
+		if err != nil || n < 2 || n > 256 {
+			return rule, fmt.Errorf("rule %q should have between 2 and 256 states", rulestring)
+		}
+		rule.States = n
+	}
+	if rule.Lattice != Square {
+		rule.Birth, rule.Survive = make([]bool, rule.Lattice.size()+1), make([]bool, rule.Lattice.size()+1)
+		if err := parseLatticeCounts(birth, rule.Birth); err != nil {
+			return rule, fmt.Errorf("rule %q: %v", rulestring, err)
+		}
+		if err := parseLatticeCounts(survive, rule.Survive); err != nil {
+			return rule, fmt.Errorf("rule %q: %v", rulestring, err)
+		}
+		return rule, nil
+	}
+	var born, survives [512]bool
+	if err := parseArrangements(birth, &born); err != nil {
+		return rule, fmt.Errorf("rule %q: %v", rulestring, err)
+	}
+	if err := parseArrangements(survive, &survives); err != nil {
+		return rule, fmt.Errorf("rule %q: %v", rulestring, err)
+	}
+	for arrangement := range born {
+		if arrangement&middleBit != 0 {
+			continue
+		}
+		n, _ := henselLetter(arrangement)
+		rule.Birth[n] = rule.Birth[n] || born[arrangement]
+		rule.Survive[n] = rule.Survive[n] || survives[arrangement]
+	}
+	rule.setTable(&born, &survives)
+	return rule, nil
+}
+
+// setTable fills in the lookup table from the arrangements of alive neighbours that dead cells are born with and
+// alive cells survive with
+func (rule *Rule) setTable(born *[512]bool, survives *[512]bool) {
+	rule.table = make([]bool, 512)
+	for bits := range rule.table {
+		if bits&middleBit != 0 {
+			rule.table[bits] = survives[bits&^middleBit]
+		} else {
+			rule.table[bits] = born[bits]
+		}
+	}
+}
+
+// parseLarger reads a Larger than Life rule in Golly's notation, e.g. "R5,C0,M1,S34..58,B34..45,NM" for Bosco's Rule:
+// the range, the number of states (0 is the same as 2), whether a cell counts itself, the numbers of alive cells that
+// an alive cell survives with and a dead cell is born with, and the neighbourhood (NM for Moore, NN for von Neumann).
+// Leaving out C, M or N gives C0, M0 or NM.
+func parseLarger(rulestring string) (Rule, error) {
+	rule := Rule{States: 2}
+	var birth, survive string
+	for _, part := range strings.Split(strings.ToUpper(rulestring), ",") {
+		if len(part) < 2 {
+			return rule, fmt.Errorf("rule %q has an empty part", rulestring)
+		}
+		var err error
+		switch part[0] {
+		case 'R':
+			rule.Range, err = strconv.Atoi(part[1:])
+			if err != nil || rule.Range < 1 || rule.Range > 500 {
+				return rule, fmt.Errorf("rule %q should have a range between 1 and 500", rulestring)
+			}
+		case 'C':
+			rule.States, err = strconv.Atoi(part[1:])
+			if err != nil || rule.States == 1 || rule.States < 0 || rule.States > 256 {
+				return rule, fmt.Errorf("rule %q should have between 2 and 256 states", rulestring)
+			}
+			if rule.States == 0 {
+				rule.States = 2
+			}
+		case 'M':
+			if part != "M0" && part != "M1" {
+				return rule, fmt.Errorf("rule %q should have M0 or M1", rulestring)
+			}
+			rule.Middle = part == "M1"
+		case 'S':
+			survive = part[1:]
+		case 'B':
+			birth = part[1:]
+		case 'N':
+			switch part {
+			case "NM":
+				rule.Neighbourhood = Moore
+			case "NN":
+				rule.Neighbourhood = VonNeumann
+			default:
+				return rule, fmt.Errorf("rule %q should have the neighbourhood NM or NN", rulestring)
+			}
+		default:
+			return rule, fmt.Errorf("rule %q has a part %q that isn't R, C, M, S, B or N", rulestring, part)
+		}
+	}
+	if birth == "" || survive == "" {
+		return rule, fmt.Errorf("rule %q should have both S and B intervals", rulestring)
+	}
+	size := rule.size()
+	rule.Birth, rule.Survive = make([]bool, size+1), make([]bool, size+1)
+	if err := parseInterval(birth, rule.Birth); err != nil {
+		return rule, fmt.Errorf("rule %q: %v", rulestring, err)
+	}
+	if err := parseInterval(survive, rule.Survive); err != nil {
+		return rule, fmt.Errorf("rule %q: %v", rulestring, err)
+	}
+	if !rule.extended() { // the same as a rule in B/S notation, so it can use the lookup table
+		var born, survives [512]bool
+		for arrangement := range born {
+			if arrangement&middleBit != 0 {
+				continue
+			}
+			n, _ := henselLetter(arrangement)
+			born[arrangement], survives[arrangement] = rule.Birth[n], rule.Survive[n]
+		}
+		rule.setTable(&born, &survives)
+	}
+	return rule, nil
+}
+
+// parseInterval sets counts[n] for every n from low to high in an interval written as "low..high"
+func parseInterval(interval string, counts []bool) error {
+	bounds := strings.SplitN(interval, "..", 2)
+	if len(bounds) != 2 {
+		return fmt.Errorf("%q is not an interval like 2..3", interval)
+	}
+	low, lowErr := strconv.Atoi(bounds[0])
+	high, highErr := strconv.Atoi(bounds[1])
+	if lowErr != nil || highErr != nil || low < 0 || high < low || high >= len(counts) {
+		return fmt.Errorf("%q is not an interval between 0 and %d", interval, len(counts)-1)
+	}
+	for n := low; n <= high; n++ {
+		counts[n] = true
+	}
+	return nil
+}
+
+// size returns the number of cells in the neighbourhood of a cell, including the cell itself
+func (rule Rule) size() int {
+	if rule.Neighbourhood == VonNeumann {
+		return 2*rule.Range*(rule.Range+1) + 1
+	}
+	return (2*rule.Range + 1) * (2*rule.Range + 1)
+}
+
+// extended checks if the rule needs anything other than the 8 cells around a cell, so it is counted from a
+// summedArea instead
+func (rule Rule) extended() bool {
+	return rule.Range != 1 || rule.Neighbourhood != Moore || rule.Middle
+}
+
+// String returns the rule in B/S notation, e.g. "B3/S23", "B2/S/C3" or "B2/S34H", in Golly's notation for Larger
+// than Life rules, e.g. "R5,C0,M1,S34..58,B34..45,NM", in Bays' notation for 3D rules, e.g. "4555", as the blocks of
+// a Margolus rule, e.g. "M0,8,4,3,2,5,9,7,1,6,10,11,12,13,14,15", followed by Immigration or QuadLife for rules
+// with colours, or the file a rule table was loaded from
+func (rule Rule) String() string {
+	if rule.transitions != nil {
+		return rule.transitions.path
+	}
+	if rule.Colours > 0 {
+		return coloursString(rule)
+	}
+	if rule.Voxels {
+		return voxelsString(rule)
+	}
+	if rule.Margolus != nil {
+		return margolusString(rule)
+	}
+	if rule.Lattice != Square {
+		var b strings.Builder
+		b.WriteString("B" + latticeCounts(rule.Birth) + "/S" + latticeCounts(rule.Survive))
+		if rule.States > 2 {
+			b.WriteString("/C" + strconv.Itoa(rule.States))
+		}
+		return b.String() + rule.Lattice.suffix()
+	}
+	if rule.extended() {
+		neighbourhood := "NM"
+		if rule.Neighbourhood == VonNeumann {
+			neighbourhood = "NN"
+		}
+		middle := 0
+		if rule.Middle {
+			middle = 1
+		}
+		states := rule.States
+		if states == 2 {
+			states = 0
+		}
+		return fmt.Sprintf("R%d,C%d,M%d,S%v,B%v,%v", rule.Range, states, middle,
+			interval(rule.Survive), interval(rule.Birth), neighbourhood)
+	}
+	var born, survives [512]bool
+	for bits := range born {
+		if bits&middleBit == 0 {
+			born[bits], survives[bits] = rule.table[bits], rule.table[bits|middleBit]
+		}
+	}
+	var b strings.Builder
+	b.WriteString("B" + henselString(&born) + "/S" + henselString(&survives))
+	if rule.States > 2 {
+		b.WriteString("/C" + strconv.Itoa(rule.States))
+	}
+	return b.String()
+}
+
+// interval writes the counts that are set as "low..high", as they are in Larger than Life rules
+func interval(counts []bool) string {
+	low, high := bounds(counts)
+	return strconv.Itoa(low) + ".." + strconv.Itoa(high)
+}
+
+// bounds returns the lowest and highest counts that are set, or -1 if none are
+func bounds(counts []bool) (int, int) {
+	low, high := -1, -1
+	for n, set := range counts {
+		if set {
+			if low < 0 {
+				low = n
+			}
+			high = n
+		}
+	}
+	return low, high
+}
+
+// shade returns the grey level a state is stored as on the board, where state 0 is dead (0), 1 is alive (255)
+// and the dying states get darker until they are dead
+func (rule Rule) shade(state int) uint8 {
+	if state == 0 {
+		return 0
+	}
+	return uint8(255 * (rule.States - state) / (rule.States - 1))
+}
+
+// state returns the state of a cell from the grey level it is stored as, rounding to the nearest dying state
+func (rule Rule) state(value uint8) int {
+	switch value {
+	case 0:
+		return 0
+	case 255:
+		return 1
+	}
+	state := rule.States - (int(value)*(rule.States-1)+127)/255
+	if state < 2 {
+		return 2
+	}
+	if state > rule.States-1 {
+		return rule.States - 1
+	}
+	return state
+}
+
+// next returns the value of a cell after a turn following an extended rule, given its value now and its number of
+// alive neighbours
+func (rule Rule) next(value uint8, aliveNeighbours int) uint8 {
+	switch value {
+	case 0:
+		return rule.after(value, rule.Birth[aliveNeighbours])
+	case 255:
+		return rule.after(value, rule.Survive[aliveNeighbours])
+	default:
+		return rule.after(value, false)
+	}
+}
+
+// lookup returns the value of a cell after a turn following a rule that isn't extended, given its value now and its
+// neighbourhood bits
+func (rule Rule) lookup(value uint8, bits int) uint8 {
+	return rule.after(value, rule.table[bits])
+}
+
+// after returns the value of a cell after a turn, given its value now and whether the rule has it alive, which
+// only matters for cells that are alive or dead now
+func (rule Rule) after(value uint8, alive bool) uint8 {
+	switch value {
+	case 0:
+		if alive {
+			return 255
+		}
+		return 0
+	case 255:
+		if alive {
+			return 255
+		}
+		return rule.shade(2 % rule.States) // starts dying, or is straight away dead with 2 states
+	default:
+		return rule.shade((rule.state(value) + 1) % rule.States)
+	}
+}
@@ -0,0 +1,446 @@
+package gol
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"sync"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// neighbours of a cell in the order rule tables list them, as dx, dy
+var (
+	mooreTableNeighbours      = [][2]int{{0, -1}, {1, -1}, {1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}} // N, NE, E, SE, S, SW, W, NW
+	vonNeumannTableNeighbours = [][2]int{{0, -1}, {1, 0}, {0, 1}, {-1, 0}}                                     // N, E, S, W
+)
+
+// ruleTable is a rule loaded from a Golly .rule file, which lists transitions from the states of a cell and its
+// neighbours to the state of the cell after a turn. The first transition that matches a cell is used, and cells that
+// no transition matches stay the same.
+type ruleTable struct {
+	path        string   // the file the table was loaded from, which is also its rulestring
+	neighbours  [][2]int // mooreTableNeighbours or vonNeumannTableNeighbours
+	transitions []transition
+	permute     bool       // whether only how many neighbours are in each state matters, not where they are
+	state       [256]uint8 // the state of each value a cell can be stored as
+	shade       []uint8    // the value each state is stored as, see Rule.shade
+	cache       sync.Map   // the next state for each tableKey already worked out, shared by every worker
+}
+
+// tableKey holds the states of a cell and then its neighbours, with the neighbours sorted if the table permutes them
+type tableKey [9]uint8
+
+// transition is one line of a rule table, or a turn or reflection of one given by the symmetries of the table
+type transition struct {
+	inputs    []tableInput // the cell and then its neighbours
+	output    int          // the state of the cell after a turn, unless variable is set
+	variable  int          // the bound variable the cell takes the state of after a turn, or -1
+	variables int          // the number of bound variables, which appear more than once in the transition
+	permute   bool         // whether the neighbours can match the inputs in any order
+	alike     []int        // the first input that matches exactly the same states as each input without a bound variable
+}
+
+// tableInput is the states one input of a transition matches
+type tableInput struct {
+	states   []bool // states[s] is set if the input matches state s
+	variable int    // the bound variable of the input, which has to be the same state everywhere it appears, or -1
+}
+
+// loadRuleTable reads a rule table from a .rule file. Only the @TABLE section is read, so the colours and icons
+// used by Golly are ignored.
+func loadRuleTable(path string) (Rule, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Rule{}, err
+	}
+	return parseRuleTable(path, string(data))
+}
+
+// parseRuleTable reads the @TABLE section of a .rule file: n_states, the neighborhood (Moore or vonNeumann), the
+// symmetries (none, rotate4, rotate8, reflect_horizontal, rotate4reflect, rotate8reflect or permute), variables such
+// as "var a={0,1,2}", and transitions such as "3,1,a,b,c,d,e,f,g,1", giving the cell, its neighbours from the north
+// going clockwise, and then the state of the cell after a turn. Transitions with single digit states and variable
+// names can leave out the commas, e.g. "012345". A variable used more than once in a transition is bound, so it has
+// to be the same state everywhere it appears, and the state after a turn can be a bound variable.
+func parseRuleTable(path string, text string) (Rule, error) {
+	table := &ruleTable{path: path}
+	rule := Rule{Range: 1, transitions: table}
+	variables := make(map[string][]bool)
+	symmetries := "none"
+	var permutations [][]int // how the neighbours move in each symmetry of the table
+	section := ""
+	for number, line := range strings.Split(text, "\n") {
+		fail := func(format string, a ...interface{}) (Rule, error) {
+			return rule, fmt.Errorf("%v:%d: %v", path, number+1, fmt.Sprintf(format, a...))
+		}
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "@") {
+			section = strings.Fields(line)[0]
+			if section == "@TREE" {
+				return fail("only rules with a @TABLE can be loaded, not a @TREE")
+			}
+			continue
+		}
+		if section != "@TABLE" {
+			continue
+		}
+		var err error
+		switch {
+		case strings.HasPrefix(line, "n_states:") || strings.HasPrefix(line, "num_states:"):
+			rule.States, err = strconv.Atoi(strings.TrimSpace(line[strings.IndexByte(line, ':')+1:]))
+			if err != nil || rule.States < 2 || rule.States > 256 {
+				return fail("a rule table should have between 2 and 256 states")
+			}
+		case strings.HasPrefix(line, "neighborhood:") || strings.HasPrefix(line, "neighbourhood:"):
+			switch neighbourhood := strings.TrimSpace(line[strings.IndexByte(line, ':')+1:]); neighbourhood {
+			case "Moore":
+				table.neighbours = mooreTableNeighbours
+			case "vonNeumann":
+				table.neighbours = vonNeumannTableNeighbours
+			default:
+				return fail("the neighborhood should be Moore or vonNeumann, not %v", neighbourhood)
+			}
+		case strings.HasPrefix(line, "symmetries:"):
+			symmetries = strings.TrimSpace(line[len("symmetries:"):])
+		case rule.States == 0 || table.neighbours == nil:
+			return fail("n_states and neighborhood should be given before any variables or transitions")
+		case strings.HasPrefix(line, "var "):
+			parts := strings.SplitN(line[len("var "):], "=", 2)
+			name := strings.TrimSpace(parts[0])
+			if len(parts) != 2 || name == "" {
+				return fail("a variable should be written like var a={0,1,2}")
+			}
+			variables[name], err = parseTableSet(strings.TrimSpace(parts[1]), rule.States, variables)
+			if err != nil {
+				return fail("%v", err)
+			}
+		default:
+			if permutations == nil {
+				permutations, table.permute, err = tableSymmetries(symmetries, len(table.neighbours))
+				if err != nil {
+					return fail("%v", err)
+				}
+			}
+			t, err := parseTransition(line, len(table.neighbours), rule.States, variables)
+			if err != nil {
+				return fail("%v", err)
+			}
+			t.permute = table.permute
+			for _, permutation := range permutations {
+				turned := t
+				turned.inputs = make([]tableInput, len(t.inputs))
+				turned.inputs[0] = t.inputs[0]
+				for k, moved := range permutation {
+					turned.inputs[1+moved] = t.inputs[1+k]
+				}
+				turned.alike = alikeInputs(turned.inputs)
+				table.transitions = append(table.transitions, turned)
+			}
+		}
+	}
+	if rule.States == 0 || table.neighbours == nil {
+		return rule, fmt.Errorf("%v: should have a @TABLE with n_states and neighborhood", path)
+	}
+	for value := range table.state {
+		table.state[value] = uint8(minInt(rule.state(uint8(value)), rule.States-1))
+	}
+	table.shade = make([]uint8, rule.States)
+	for state := range table.shade {
+		table.shade[state] = rule.shade(state)
+	}
+	rule.Birth = []bool{table.nextValue(func(int, int) uint8 { return 0 }) != 0} // so empty tiles aren't skipped, and unbounded boards are refused
+	return rule, nil
+}
+
+// parseTableSet reads a set of states such as "{0,1,a}", where variables stand for all of their states
+func parseTableSet(text string, states int, variables map[string][]bool) ([]bool, error) {
+	if !strings.HasPrefix(text, "{") || !strings.HasSuffix(text, "}") {
+		return nil, fmt.Errorf("%q should be a set of states like {0,1,2}", text)
+	}
+	set := make([]bool, states)
+	for _, element := range strings.Split(text[1:len(text)-1], ",") {
+		element = strings.TrimSpace(element)
+		if variable, ok := variables[element]; ok {
+			for state, in := range variable {
+				set[state] = set[state] || in
+			}
+			continue
+		}
+		state, err := strconv.Atoi(element)
+		if err != nil || state < 0 || state >= states {
+			return nil, fmt.Errorf("%q is not a state or a variable", element)
+		}
+		set[state] = true
+	}
+	return set, nil
+}
+
+// parseTransition reads the cell, its neighbours and the state after a turn from one line of a rule table
+func parseTransition(line string, neighbours int, states int, variables map[string][]bool) (transition, error) {
+	t := transition{variable: -1}
+	tokens := splitTransition(line)
+	if len(tokens) != neighbours+2 {
+		return t, fmt.Errorf("a transition should have %d states, not %d", neighbours+2, len(tokens))
+	}
+	uses := make(map[string]int)
+	for _, token := range tokens {
+		if _, ok := variables[token]; ok {
+			uses[token]++
+		}
+	}
+	bound := make(map[string]int) // the index of each bound variable
+	for i, token := range tokens {
+		input := tableInput{variable: -1}
+		if variable, ok := variables[token]; ok {
+			input.states = variable
+			if uses[token] > 1 {
+				if _, ok := bound[token]; !ok {
+					bound[token] = len(bound)
+				}
+				input.variable = bound[token]
+			}
+		} else if strings.HasPrefix(token, "{") {
+			set, err := parseTableSet(token, states, variables)
+			if err != nil {
+				return t, err
+			}
+			input.states = set
+		} else {
+			state, err := strconv.Atoi(token)
+			if err != nil || state < 0 || state >= states {
+				return t, fmt.Errorf("%q is not a state or a variable", token)
+			}
+			input.states = make([]bool, states)
+			input.states[state] = true
+		}
+		if i == len(tokens)-1 { // the state after a turn
+			if input.variable >= 0 {
+				t.variable = input.variable
+			} else if state, err := strconv.Atoi(token); err == nil {
+				t.output = state
+			} else {
+				return t, fmt.Errorf("%q should be a state or a variable from earlier in the transition", token)
+			}
+			break
+		}
+		t.inputs = append(t.inputs, input)
+	}
+	t.variables = len(bound)
+	return t, nil
+}
+
+// splitTransition splits a transition at the commas outside of sets, or into single characters if it has no commas
+func splitTransition(line string) []string {
+	var tokens []string
+	if !strings.Contains(line, ",") {
+		for _, char := range line {
+			if char != ' ' && char != '\t' {
+				tokens = append(tokens, string(char))
+			}
+		}
+		return tokens
+	}
+	depth, start := 0, 0
+	for i, char := range line {
+		switch char {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				tokens = append(tokens, strings.TrimSpace(line[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(tokens, strings.TrimSpace(line[start:]))
+}
+
+// tableSymmetries returns how the neighbours move in each turn and reflection of the named symmetries, where
+// neighbour k moves to permutation[k], and whether the neighbours can be in any order
+func tableSymmetries(name string, neighbours int) ([][]int, bool, error) {
+	turns := func(step int, reflect bool) [][]int {
+		var permutations [][]int
+		for turn := 0; turn < neighbours; turn += step {
+			permutation := make([]int, neighbours)
+			for k := range permutation {
+				permutation[k] = (k + turn) % neighbours
+				if reflect { // left to right, so N and S stay where they are
+					permutation[k] = (neighbours - permutation[k]) % neighbours
+				}
+			}
+			permutations = append(permutations, permutation)
+		}
+		return permutations
+	}
+	switch {
+	case name == "none" || name == "permute":
+		return turns(neighbours, false), name == "permute", nil
+	case name == "rotate4":
+		return turns(neighbours/4, false), false, nil
+	case name == "rotate8" && neighbours == 8:
+		return turns(1, false), false, nil
+	case name == "reflect_horizontal":
+		return append(turns(neighbours, false), turns(neighbours, true)...), false, nil
+	case name == "rotate4reflect":
+		return append(turns(neighbours/4, false), turns(neighbours/4, true)...), false, nil
+	case name == "rotate8reflect" && neighbours == 8:
+		return append(turns(1, false), turns(1, true)...), false, nil
+	}
+	return nil, false, fmt.Errorf("%q are not symmetries of a neighborhood of %d cells", name, neighbours)
+}
+
+// alikeInputs returns the first input that matches exactly the same states as each input without a bound variable,
+// so that neighbours are only tried against one of them when they can be in any order
+func alikeInputs(inputs []tableInput) []int {
+	alike := make([]int, len(inputs))
+	for i, input := range inputs {
+		alike[i] = i
+		for j := 1; j < i && input.variable < 0; j++ {
+			if inputs[j].variable < 0 && sameStates(inputs[j].states, input.states) {
+				alike[i] = j
+				break
+			}
+		}
+	}
+	return alike
+}
+
+// sameStates checks if two inputs match the same states
+func sameStates(a []bool, b []bool) bool {
+	for state := range a {
+		if a[state] != b[state] {
+			return false
+		}
+	}
+	return true
+}
+
+// accept checks if an input matches a state, binding its variable to the state if it isn't bound yet. It returns
+// whether it matched and whether it bound the variable, so that can be undone.
+func (input tableInput) accept(state uint8, bound *[9]int) (bool, bool) {
+	if !input.states[state] {
+		return false, false
+	}
+	if input.variable < 0 {
+		return true, false
+	}
+	switch bound[input.variable] {
+	case -1:
+		bound[input.variable] = int(state)
+		return true, true
+	case int(state):
+		return true, false
+	}
+	return false, false
+}
+
+// apply returns the state of a cell after a turn if the transition matches the states of it and its neighbours
+func (t *transition) apply(states []uint8) (uint8, bool) {
+	var bound [9]int // a transition has 10 states, so at most 5 bound variables
+	for i := range bound {
+		bound[i] = -1
+	}
+	if ok, _ := t.inputs[0].accept(states[0], &bound); !ok {
+		return 0, false
+	}
+	if t.permute {
+		if !t.matchAnyOrder(states[1:], 1, &bound) {
+			return 0, false
+		}
+	} else {
+		for k := 1; k < len(t.inputs); k++ {
+			if ok, _ := t.inputs[k].accept(states[k], &bound); !ok {
+				return 0, false
+			}
+		}
+	}
+	if t.variable >= 0 {
+		return uint8(bound[t.variable]), true
+	}
+	return uint8(t.output), true
+}
+
+// matchAnyOrder checks if the neighbours can match the inputs that aren't used yet in some order, trying the first
+// neighbour against each input that isn't alike another one already tried
+func (t *transition) matchAnyOrder(neighbours []uint8, used uint16, bound *[9]int) bool {
+	if len(neighbours) == 0 {
+		return true
+	}
+	var tried uint16
+	for i := 1; i < len(t.inputs); i++ {
+		if used&(1<<uint(i)) != 0 || tried&(1<<uint(t.alike[i])) != 0 {
+			continue
+		}
+		tried |= 1 << uint(t.alike[i])
+		ok, set := t.inputs[i].accept(neighbours[0], bound)
+		if ok && t.matchAnyOrder(neighbours[1:], used|1<<uint(i), bound) {
+			return true
+		}
+		if set {
+			bound[t.inputs[i].variable] = -1
+		}
+	}
+	return false
+}
+
+// nextValue returns the value of a cell after a turn, given a function returning the value of the cell dx, dy from it.
+// The state after a turn is worked out once for each tableKey and then looked up.
+func (table *ruleTable) nextValue(get func(dx int, dy int) uint8) uint8 {
+	var key tableKey
+	key[0] = table.state[get(0, 0)]
+	for k, offset := range table.neighbours {
+		key[k+1] = table.state[get(offset[0], offset[1])]
+	}
+	if table.permute { // insertion sort, as there are at most 8
+		for i := 2; i <= len(table.neighbours); i++ {
+			for j := i; j > 1 && key[j-1] > key[j]; j-- {
+				key[j-1], key[j] = key[j], key[j-1]
+			}
+		}
+	}
+	if next, ok := table.cache.Load(key); ok {
+		return table.shade[next.(uint8)]
+	}
+	next := key[0]
+	for i := range table.transitions {
+		if state, ok := table.transitions[i].apply(key[:len(table.neighbours)+1]); ok {
+			next = state
+			break
+		}
+	}
+	table.cache.Store(key, next)
+	return table.shade[next]
+}
+
+// AdvanceTableSection is AdvanceSection for rule tables, looking up the states of each cell and its neighbours
+// accounting for wrap around
+func (game *Game) AdvanceTableSection(startX int, endX int, startY int, endY int) bool {
+	var flipped []util.Cell // only used when batching, otherwise each flip is sent straight away
+	board := game.current
+	changed := false
+	for j := startY; j < endY; j++ {
+		for i := startX; i < endX; i++ {
+			value := board.Get(i, j)
+			newValue := game.rule.transitions.nextValue(func(dx int, dy int) uint8 {
+				return board.Get(wrap(i+dx, board.width), wrap(j+dy, board.height))
+			})
+			game.advanced.Set(i, j, newValue)
+			if newValue != value {
+				changed = true
+				game.cellChanged(util.Cell{X: i, Y: j}, newValue, &flipped)
+			}
+		}
+	}
+	game.SendFlips(flipped)
+	return changed
+}
@@ -0,0 +1,168 @@
+package gol
+
+import "sync"
+
+const tileSize = 16 // width and height of the tiles the board is split into, apart from the last ones in each row and column
+
+// tile is a rectangle of the board that one worker advances at a time
+type tile struct {
+	index  int // position in Board.tileAlive
+	startX int
+	endX   int
+	startY int
+	endY   int
+	tileX  int // column of the tile
+	tileY  int // row of the tile
+}
+
+// createTiles splits the board into tiles in reading order
+func (board *Board) createTiles() {
+	board.tilesX = (board.width + tileSize - 1) / tileSize
+	board.tilesY = (board.height + tileSize - 1) / tileSize
+	board.tiles = make([]tile, 0, board.tilesX*board.tilesY)
+	for ty := 0; ty < board.tilesY; ty++ {
+		for tx := 0; tx < board.tilesX; tx++ {
+			board.tiles = append(board.tiles, tile{
+				index:  len(board.tiles),
+				startX: tx * tileSize,
+				endX:   minInt(board.width, (tx+1)*tileSize),
+				startY: ty * tileSize,
+				endY:   minInt(board.height, (ty+1)*tileSize),
+				tileX:  tx,
+				tileY:  ty,
+			})
+		}
+	}
+	board.tileAlive = make([]int, len(board.tiles))
+}
+
+// minInt returns the smaller of two ints
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// maxInt returns the larger of two ints
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// countTiles recounts the cells that aren't dead in every tile, after the board has been loaded
+func (board *Board) countTiles() {
+	for _, t := range board.tiles {
+		board.tileAlive[t.index] = board.countTile(t)
+	}
+}
+
+// countTile counts the cells that aren't dead in one tile, including dying cells
+func (board *Board) countTile(t tile) int {
+	count := 0
+	for j := t.startY; j < t.endY; j++ {
+		for i := t.startX; i < t.endX; i++ {
+			if board.Get(i, j) != 0 {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// clearTile kills every cell in a tile
+func (board *Board) clearTile(t tile) {
+	for j := t.startY; j < t.endY; j++ {
+		for i := t.startX; i < t.endX; i++ {
+			board.Set(i, j, 0)
+		}
+	}
+	board.tileAlive[t.index] = 0
+}
+
+// tileAt returns the index of the tile holding a cell
+func (board *Board) tileAt(x int, y int) int {
+	return (y/tileSize)*board.tilesX + x/tileSize
+}
+
+// neighbourhoodEmpty checks if every cell in a tile and the tiles within reach of it is dead, accounting for wrap
+// around. Unless the rule gives birth to cells with no alive neighbours, the cells in such a tile will all still be
+// dead after the next turn.
+func (board *Board) neighbourhoodEmpty(t tile, reach int) bool {
+	for dy := -reach; dy <= reach; dy++ {
+		for dx := -reach; dx <= reach; dx++ {
+			tx := wrap(t.tileX+dx, board.tilesX)
+			ty := wrap(t.tileY+dy, board.tilesY)
+			if board.tileAlive[ty*board.tilesX+tx] > 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// neighbourhoodChanged checks if a tile or any tile within reach of it changed on the last turn, accounting for wrap
+// around. If none did, the cells in the tile see the same neighbours as last turn, so they will stay as they are.
+func (game *Game) neighbourhoodChanged(t tile, reach int) bool {
+	board := game.current
+	for dy := -reach; dy <= reach; dy++ {
+		for dx := -reach; dx <= reach; dx++ {
+			tx := wrap(t.tileX+dx, board.tilesX)
+			ty := wrap(t.tileY+dy, board.tilesY)
+			if game.tileChanged[ty*board.tilesX+tx] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AdvanceTile advances one tile of the board one turn and counts the cells it leaves in the tile that aren't dead
+func (game *Game) AdvanceTile(t tile) {
+	game.tileChanged[t.index] = game.AdvanceSection(t.startX, t.endX, t.startY, t.endY)
+	game.advanced.tileAlive[t.index] = game.advanced.countTile(t)
+}
+
+// Advance queues every tile that could change this turn, then hands the queue to the workers, which take tiles from it
+// until it is empty. Only tiles around the changes of the last turn are queued, and of those, tiles with only dead
+// cells around them are skipped as well, so sparse or settled boards are spread between the workers by the tiles that need
+// work instead of by area. Rules with a larger range look further for changes and alive cells, as each worker reads a
+// halo of Range cells around its tile.
+//
+// A tile that didn't change last turn holds the same cells on both boards, so skipping it leaves the advanced board
+// correct without copying. An empty tile that did change still holds the cells from two turns ago on the advanced
+// board, so it is cleared instead.
+func (game *Game) Advance(wg *sync.WaitGroup, workers int) {
+	tiles := game.current.tiles
+	reach := game.rule.reach(tileSize)
+	active := make([]bool, len(tiles)) // worked out before tileChanged is updated for this turn
+	for _, t := range tiles {
+		// noise can change any tile, and a tile that a Margolus rule left as it was can change with the blocks the other way round
+		active[t.index] = game.noise > 0 || game.rule.Margolus != nil || game.neighbourhoodChanged(t, reach)
+	}
+	if game.rule.countsArea() {
+		game.area = game.current.summedArea()
+	}
+	queue := make(chan tile, len(tiles))
+	for _, t := range tiles {
+		switch {
+		case !active[t.index]:
+			game.tileChanged[t.index] = false
+		case !game.rule.Birth[0] && game.noise == 0 && game.current.neighbourhoodEmpty(t, reach):
+			if game.advanced.tileAlive[t.index] > 0 { // the advanced board still has the cells from two turns ago
+				game.advanced.clearTile(t)
+			}
+			game.tileChanged[t.index] = false
+		default:
+			queue <- t
+		}
+	}
+	close(queue)
+	game.startTurn(wg, workers, func(int) {
+		for t := range queue {
+			game.AdvanceTile(t)
+		}
+	})
+}
@@ -0,0 +1,16 @@
+package gol
+
+// Unbatch forwards every event from in to out, splitting each CellsFlipped into one CellFlipped per cell,
+// so consumers written for CellFlipped keep working when Params.BatchEvents is set. It closes out once in is closed.
+func Unbatch(in <-chan Event, out chan<- Event) {
+	for event := range in {
+		if batch, ok := event.(CellsFlipped); ok {
+			for _, cell := range batch.Cells {
+				out <- CellFlipped{CompletedTurns: batch.CompletedTurns, Cell: cell}
+			}
+			continue
+		}
+		out <- event
+	}
+	close(out)
+}
@@ -0,0 +1,306 @@
+package gol
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+const chunkSize = 32 // width and height of the chunks the universe is stored in
+
+// chunkKey is the position of a chunk, in chunks rather than cells, so the chunk at 0, 0 holds cells 0-31 and the
+// chunk at -1, 0 holds cells -32 to -1
+type chunkKey struct {
+	x int
+	y int
+}
+
+// chunk stores the cells of one square of the universe
+type chunk struct {
+	cells    [chunkSize * chunkSize]uint8 // cells[y*chunkSize+x]
+	occupied int                          // number of cells that aren't dead, including dying cells
+}
+
+// chunkJob is one chunk for a worker to advance, along with where to put the result
+type chunkJob struct {
+	key   chunkKey
+	index int
+}
+
+// Universe stores a board without edges as chunks of cells, so it can grow in any direction and only the areas
+// with something alive in them take up memory. Cells can have negative coordinates.
+type Universe struct {
+	chunks map[chunkKey]*chunk
+	keys   []chunkKey // the chunks being advanced this turn
+	next   []*chunk   // the advanced chunks, in the same order as keys
+}
+
+// checkUnbounded makes sure an unbounded game has a rule that leaves empty space empty. Only chunks next to ones with
+// something in them are advanced, so a rule that gives birth with no alive cells around, e.g. B0 rules, Margolus rules
+// that fill empty blocks or rule tables with births from the quiescent state, would fill the endless board forever.
+func checkUnbounded(p Params, rule Rule) error {
+	if p.Unbounded && rule.Birth[0] {
+		return errors.New(rule.String() + " gives birth in empty space, so it can't be used on an unbounded board")
+	}
+	return nil
+}
+
+// newUniverse creates an empty universe
+func newUniverse() *Universe {
+	return &Universe{chunks: make(map[chunkKey]*chunk)}
+}
+
+// floorDiv divides, rounding towards negative infinity, so negative cells end up in negative chunks
+func floorDiv(a int, b int) int {
+	if a < 0 {
+		return (a - b + 1) / b
+	}
+	return a / b
+}
+
+// locate returns the chunk holding a cell and the cell's position inside it
+func locate(x int, y int) (chunkKey, int) {
+	key := chunkKey{floorDiv(x, chunkSize), floorDiv(y, chunkSize)}
+	return key, (y-key.y*chunkSize)*chunkSize + (x - key.x*chunkSize)
+}
+
+// Get retrieves the value of a cell
+func (universe *Universe) Get(x int, y int) uint8 {
+	key, i := locate(x, y)
+	c, ok := universe.chunks[key]
+	if !ok {
+		return 0
+	}
+	return c.cells[i]
+}
+
+// Set sets the value of a cell, adding or removing its chunk as needed
+func (universe *Universe) Set(x int, y int, val uint8) {
+	key, i := locate(x, y)
+	c, ok := universe.chunks[key]
+	if !ok {
+		if val == 0 {
+			return
+		}
+		c = &chunk{}
+		universe.chunks[key] = c
+	}
+	if c.cells[i] != 0 {
+		c.occupied--
+	}
+	if val != 0 {
+		c.occupied++
+	}
+	c.cells[i] = val
+	if c.occupied == 0 {
+		delete(universe.chunks, key)
+	}
+}
+
+// AliveCells returns a list of Cells that are alive, in reading order
+func (universe *Universe) AliveCells() []util.Cell {
+	var aliveCells []util.Cell
+	for key, c := range universe.chunks {
+		for i, value := range c.cells {
+			if value == 255 {
+				aliveCells = append(aliveCells, util.Cell{X: key.x*chunkSize + i%chunkSize, Y: key.y*chunkSize + i/chunkSize})
+			}
+		}
+	}
+	sort.Slice(aliveCells, func(i, j int) bool {
+		return aliveCells[i].Y < aliveCells[j].Y || aliveCells[i].Y == aliveCells[j].Y && aliveCells[i].X < aliveCells[j].X
+	})
+	return aliveCells
+}
+
+// Count returns the number of alive cells
+func (universe *Universe) Count() int {
+	count := 0
+	for _, c := range universe.chunks {
+		for _, value := range c.cells {
+			if value == 255 {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// Bounds returns the smallest rectangle holding every cell that isn't dead, from minimum up to but not including
+// maximum, or false if every cell is dead
+func (universe *Universe) Bounds() (util.Cell, util.Cell, bool) {
+	found := false
+	var low, high util.Cell
+	for key, c := range universe.chunks {
+		for i, value := range c.cells {
+			if value == 0 {
+				continue
+			}
+			cell := util.Cell{X: key.x*chunkSize + i%chunkSize, Y: key.y*chunkSize + i/chunkSize}
+			if !found {
+				low, high, found = cell, cell, true
+			}
+			if cell.X < low.X {
+				low.X = cell.X
+			}
+			if cell.X > high.X {
+				high.X = cell.X
+			}
+			if cell.Y < low.Y {
+				low.Y = cell.Y
+			}
+			if cell.Y > high.Y {
+				high.Y = cell.Y
+			}
+		}
+	}
+	return low, util.Cell{X: high.X + 1, Y: high.Y + 1}, found
+}
+
+// neighbourhood returns the chunk at key and the 8 chunks around it, as neighbourhood[dy+1][dx+1], with nil for
+// chunks that don't exist
+func (universe *Universe) neighbourhood(key chunkKey) [3][3]*chunk {
+	var chunks [3][3]*chunk
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			chunks[dy+1][dx+1] = universe.chunks[chunkKey{key.x + dx, key.y + dy}]
+		}
+	}
+	return chunks
+}
+
+// alive checks if a cell is alive, where x and y are relative to the middle chunk and may be just outside it
+func alive(chunks *[3][3]*chunk, x int, y int) bool {
+	return cellValue(chunks, x, y) == 255
+}
+
+// cellValue returns the value of a cell, where x and y are relative to the middle chunk and may be just outside it
+func cellValue(chunks *[3][3]*chunk, x int, y int) uint8 {
+	cx, cy := 1, 1
+	if x < 0 {
+		cx, x = 0, x+chunkSize
+	} else if x >= chunkSize {
+		cx, x = 2, x-chunkSize
+	}
+	if y < 0 {
+		cy, y = 0, y+chunkSize
+	} else if y >= chunkSize {
+		cy, y = 2, y-chunkSize
+	}
+	return chunks[cy][cx].get(x, y)
+}
+
+// get returns the value of a cell in a chunk that may not exist
+func (c *chunk) get(x int, y int) uint8 {
+	if c == nil {
+		return 0
+	}
+	return c.cells[y*chunkSize+x]
+}
+
+// AdvanceChunk works out one chunk of the universe after a turn following the rule, sending the cells that change.
+// It returns nil if every cell in the chunk is dead after the turn.
+func (game *Game) AdvanceChunk(key chunkKey) *chunk {
+	chunks := game.universe.neighbourhood(key)
+	advanced := &chunk{}
+	var flipped []util.Cell // only used when batching, otherwise each flip is sent straight away
+	var table *summedArea   // only used for rules that reach further than the 8 cells around each cell
+	if r := game.rule.Range; game.rule.extended() {
+		table = newSummedArea(key.x*chunkSize-r, key.y*chunkSize-r, chunkSize+2*r, chunkSize+2*r, func(x int, y int) bool {
+			return game.universe.Get(x, y) == 255
+		})
+	}
+	for j := 0; j < chunkSize; j++ {
+		for i := 0; i < chunkSize; i++ {
+			value := chunks[1][1].get(i, j)
+			var newValue uint8
+			switch {
+			case game.rule.transitions != nil:
+				newValue = game.rule.transitions.nextValue(func(dx int, dy int) uint8 {
+					return cellValue(&chunks, i+dx, j+dy)
+				})
+			case game.rule.Margolus != nil: // the chunks start on even cells, so i and j have the same parity as the cell
+				newValue = game.margolusNext(i, j, value, func(x int, y int) bool {
+					return alive(&chunks, x, y)
+				})
+			case game.rule.Lattice != Square:
+				aliveNeighbours := 0
+				for _, offset := range game.rule.Lattice.neighbours(key.x*chunkSize+i, key.y*chunkSize+j) {
+					if alive(&chunks, i+offset[0], j+offset[1]) {
+						aliveNeighbours++
+					}
+				}
+				newValue = game.rule.next(value, aliveNeighbours)
+			case table != nil:
+				newValue = game.rule.next(value, table.neighbours(game.rule, key.x*chunkSize+i, key.y*chunkSize+j, value == 255))
+			default:
+				bits := 0
+				for dy := -1; dy <= 1; dy++ {
+					for dx := -1; dx <= 1; dx++ {
+						if alive(&chunks, i+dx, j+dy) {
+							bits |= 1 << uint((dy+1)*3+dx+1)
+						}
+					}
+				}
+				newValue = game.rule.lookup(value, bits)
+			}
+			if newValue != 0 {
+				advanced.cells[j*chunkSize+i] = newValue
+				advanced.occupied++
+			}
+			if newValue != value {
+				game.cellChanged(util.Cell{X: key.x*chunkSize + i, Y: key.y*chunkSize + j}, newValue, &flipped)
+			}
+		}
+	}
+	game.SendFlips(flipped)
+	if advanced.occupied == 0 {
+		return nil
+	}
+	return advanced
+}
+
+// AdvanceUniverse queues every chunk with something that isn't dead in it or within reach of it, then hands the queue to
+// the workers, which take chunks from it until it is empty. The advanced chunks replace the current ones when Swap is called.
+func (game *Game) AdvanceUniverse(wg *sync.WaitGroup, workers int) {
+	universe := game.universe
+	reach := game.rule.reach(chunkSize)
+	queued := make(map[chunkKey]bool)
+	universe.keys = universe.keys[:0]
+	for key := range universe.chunks {
+		for dy := -reach; dy <= reach; dy++ {
+			for dx := -reach; dx <= reach; dx++ {
+				neighbour := chunkKey{key.x + dx, key.y + dy}
+				if !queued[neighbour] {
+					queued[neighbour] = true
+					universe.keys = append(universe.keys, neighbour)
+				}
+			}
+		}
+	}
+	universe.next = make([]*chunk, len(universe.keys))
+	queue := make(chan chunkJob, len(universe.keys))
+	for i, key := range universe.keys {
+		queue <- chunkJob{key: key, index: i}
+	}
+	close(queue)
+	game.startTurn(wg, workers, func(int) {
+		for job := range queue {
+			universe.next[job.index] = game.AdvanceChunk(job.key)
+		}
+	})
+}
+
+// Swap replaces the chunks with the ones advanced by the workers
+func (universe *Universe) Swap() {
+	chunks := make(map[chunkKey]*chunk, len(universe.keys))
+	for i, key := range universe.keys {
+		if universe.next[i] != nil {
+			chunks[key] = universe.next[i]
+		}
+	}
+	universe.chunks = chunks
+	universe.next = nil
+}
@@ -0,0 +1,205 @@
+package gol
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// parseVoxels reads a 3D rule in Bays' notation, e.g. "4555": an alive cell survives with 4 to 5 alive cells around
+// it, and a dead cell is born with 5 to 5, out of the 26 cells around it. Counts above 9 are separated by commas,
+// e.g. "4,5,5,5" or "10,21,10,21".
+func parseVoxels(rulestring string) (Rule, error) {
+	rule := Rule{Birth: make([]bool, 27), Survive: make([]bool, 27), States: 2, Range: 1, Voxels: true}
+	numbers := strings.Split(rulestring, ",")
+	if !strings.Contains(rulestring, ",") {
+		numbers = strings.Split(rulestring, "")
+	}
+	if len(numbers) != 4 {
+		return rule, fmt.Errorf("3D rule %q should have 4 numbers: the fewest and most alive cells to survive and to be born", rulestring)
+	}
+	var counts [4]int
+	for i, number := range numbers {
+		n, err := strconv.Atoi(number)
+		if err != nil || n > 26 {
+			return rule, fmt.Errorf("3D rule %q: %q is not a number of neighbours between 0 and 26", rulestring, number)
+		}
+		counts[i] = n
+	}
+	if counts[0] > counts[1] || counts[2] > counts[3] {
+		return rule, fmt.Errorf("3D rule %q should have the fewest alive cells before the most", rulestring)
+	}
+	for n := counts[0]; n <= counts[1]; n++ {
+		rule.Survive[n] = true
+	}
+	for n := counts[2]; n <= counts[3]; n++ {
+		rule.Birth[n] = true
+	}
+	return rule, nil
+}
+
+// voxelsString writes a 3D rule in Bays' notation, separating the counts with commas if any of them are more than 9
+func voxelsString(rule Rule) string {
+	surviveLow, surviveHigh := bounds(rule.Survive)
+	birthLow, birthHigh := bounds(rule.Birth)
+	counts := []int{surviveLow, surviveHigh, birthLow, birthHigh}
+	numbers := make([]string, len(counts))
+	separator := ""
+	for i, n := range counts {
+		numbers[i] = strconv.Itoa(n)
+		if n > 9 {
+			separator = ","
+		}
+	}
+	return strings.Join(numbers, separator)
+}
+
+// checkVolume makes sure a 3D board is only used with a 3D rule, and is at least 3 cells in every direction so the 26
+// neighbours of a cell wrap around to 26 different cells rather than counting some twice or the cell itself
+func checkVolume(p Params, rule Rule) error {
+	switch {
+	case !rule.Voxels && p.Depth != 0:
+		return fmt.Errorf("a depth of %d can only be used with a 3D rule such as 4555", p.Depth)
+	case !rule.Voxels:
+		return nil
+	case p.Depth < 3 || p.ImageWidth < 3 || p.ImageHeight < 3:
+		return fmt.Errorf("3D rule %v needs a board of at least 3x3x3, not %dx%dx%d", rule, p.ImageWidth, p.ImageHeight, p.Depth)
+	case p.Unbounded:
+		return errors.New("a 3D board can't be unbounded")
+	}
+	return nil
+}
+
+// Volume stores the board of a game with a 3D rule as Depth slices of ImageWidth by ImageHeight cells, which wraps
+// around in every direction. It is read from and written to one image of the slices one under another.
+type Volume struct {
+	cells  []uint8 // cells[(z*height+y)*width+x]
+	next   []uint8 // the cells after the turn being worked out
+	width  int
+	height int
+	depth  int
+}
+
+// newVolume creates an empty volume
+func newVolume(width int, height int, depth int) *Volume {
+	return &Volume{
+		cells:  make([]uint8, width*height*depth),
+		next:   make([]uint8, width*height*depth),
+		width:  width,
+		height: height,
+		depth:  depth,
+	}
+}
+
+// Get retrieves the value of a cell, where y counts down through every slice, so slice z starts at y = z*height
+func (volume *Volume) Get(x int, y int) uint8 {
+	return volume.cells[y*volume.width+x]
+}
+
+// Set sets the value of a cell, where y counts down through every slice as with Get
+func (volume *Volume) Set(x int, y int, value uint8) {
+	volume.cells[y*volume.width+x] = value
+}
+
+// neighbours counts the alive cells in the 26 around a cell, accounting for wrap around
+func (volume *Volume) neighbours(x int, y int, z int) int {
+	count := 0
+	for dz := -1; dz <= 1; dz++ {
+		for dy := -1; dy <= 1; dy++ {
+			row := (wrap(z+dz, volume.depth)*volume.height + wrap(y+dy, volume.height)) * volume.width
+			for dx := -1; dx <= 1; dx++ {
+				if (dx != 0 || dy != 0 || dz != 0) && volume.cells[row+wrap(x+dx, volume.width)] == 255 {
+					count++
+				}
+			}
+		}
+	}
+	return count
+}
+
+// Count returns the number of alive cells in every slice
+func (volume *Volume) Count() int {
+	count := 0
+	for _, value := range volume.cells {
+		if value == 255 {
+			count++
+		}
+	}
+	return count
+}
+
+// AliveCells returns the alive cells in one slice
+func (volume *Volume) AliveCells(z int) []util.Cell {
+	var aliveCells []util.Cell
+	for j := 0; j < volume.height; j++ {
+		for i := 0; i < volume.width; i++ {
+			if volume.Get(i, z*volume.height+j) == 255 {
+				aliveCells = append(aliveCells, util.Cell{X: i, Y: j})
+			}
+		}
+	}
+	return aliveCells
+}
+
+// Swap replaces the cells with the ones advanced by the workers
+func (volume *Volume) Swap() {
+	volume.cells, volume.next = volume.next, volume.cells
+}
+
+// AdvanceSlab works out the slices from startZ up to but not including endZ after a turn. Only the cells that change
+// in the slice being shown are sent, as events only have room for x and y.
+func (game *Game) AdvanceSlab(startZ int, endZ int) {
+	var flipped []util.Cell // only used when batching, otherwise each flip is sent straight away
+	volume := game.volume
+	for z := startZ; z < endZ; z++ {
+		for j := 0; j < volume.height; j++ {
+			for i := 0; i < volume.width; i++ {
+				index := (z*volume.height+j)*volume.width + i
+				value := volume.cells[index]
+				newValue := game.rule.next(value, volume.neighbours(i, j, z))
+				volume.next[index] = newValue
+				if newValue != value && z == game.slice {
+					game.cellChanged(util.Cell{X: i, Y: j}, newValue, &flipped)
+				}
+			}
+		}
+	}
+	game.SendFlips(flipped)
+}
+
+// AdvanceVolume splits the slices between the workers as slabs as even as they can be, with no more workers than
+// slices, then gives each worker its slab. The advanced cells replace the current ones when Swap is called.
+func (game *Game) AdvanceVolume(wg *sync.WaitGroup, workers int) {
+	workers = minInt(workers, game.volume.depth)
+	game.startTurn(wg, workers, func(worker int) {
+		game.AdvanceSlab(worker*game.volume.depth/workers, (worker+1)*game.volume.depth/workers)
+	})
+}
+
+// ShowSlice is a Command that changes which slice of a 3D board events are sent for, sending the cells that differ
+// between the two slices so viewers show the new one. It does nothing for other boards.
+type ShowSlice struct {
+	Z int
+}
+
+func (command ShowSlice) apply(game *Game) {
+	if game.volume == nil {
+		return
+	}
+	volume := game.volume
+	z := wrap(command.Z, volume.depth)
+	var flipped []util.Cell
+	for j := 0; j < volume.height; j++ {
+		for i := 0; i < volume.width; i++ {
+			if value := volume.Get(i, z*volume.height+j); value != volume.Get(i, game.slice*volume.height+j) {
+				game.cellChanged(util.Cell{X: i, Y: j}, value, &flipped)
+			}
+		}
+	}
+	game.slice = z
+	game.SendFlips(flipped)
+}
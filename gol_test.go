@@ -7,6 +7,7 @@ import (
 	"strings"
 	"testing"
 
+	"uk.ac.bris.cs/gameoflife/diff"
 	"uk.ac.bris.cs/gameoflife/gol"
 	"uk.ac.bris.cs/gameoflife/util"
 )
@@ -46,10 +47,226 @@ func TestGol(t *testing.T) {
 	}
 }
 
+// TestBatchEvents tests that the CellsFlipped events of a 64x64 image over 100 turns, split by Unbatch, rebuild the expected board.
+func TestBatchEvents(t *testing.T) {
+	p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 100, Threads: 8, BatchEvents: true}
+	expectedAlive := readAliveCells("check/images/64x64x100.pgm", p.ImageWidth, p.ImageHeight)
+	events := make(chan gol.Event)
+	unbatched := make(chan gol.Event)
+	go gol.Run(p, events, nil)
+	go gol.Unbatch(events, unbatched)
+	board := make(map[util.Cell]bool)
+	for event := range unbatched {
+		switch e := event.(type) {
+		case gol.CellsFlipped:
+			t.Fatal("CellsFlipped event was not split by Unbatch")
+		case gol.CellFlipped:
+			board[e.Cell] = !board[e.Cell]
+		}
+	}
+	var cells []util.Cell
+	for cell, alive := range board {
+		if alive {
+			cells = append(cells, cell)
+		}
+	}
+	assertEqualBoard(t, cells, expectedAlive, p)
+}
+
+// TestSetThreads tests that changing the number of threads part way through a game uses that many workers from the
+// next turn on and still gives the expected images
+func TestSetThreads(t *testing.T) {
+	changes := map[int]int{10: 1, 25: 16, 50: 3, 75: 7} // threads to change to once each turn has completed
+	for _, p := range []gol.Params{
+		{ImageWidth: 64, ImageHeight: 64, Turns: 100, Threads: 8, TurnStats: true},
+		{ImageWidth: 512, ImageHeight: 512, Turns: 100, Threads: 2, TurnStats: true},
+	} {
+		expectedAlive := readAliveCells(
+			fmt.Sprintf("check/images/%vx%vx%v.pgm", p.ImageWidth, p.ImageHeight, p.Turns),
+			p.ImageWidth,
+			p.ImageHeight,
+		)
+		commands := make(chan gol.Command, len(changes))
+		events := make(chan gol.Event)
+		go gol.RunWithCommands(p, events, nil, commands)
+		threads := p.Threads
+		for event := range events {
+			switch e := event.(type) {
+			case gol.TurnStats:
+				if len(e.Workers) != threads {
+					t.Errorf("turn %v of %vx%v should have had %v workers, not %v", e.CompletedTurns, p.ImageWidth, p.ImageHeight, threads, len(e.Workers))
+				}
+				// the game is waiting to send TurnComplete, so the command is there before the next turn starts
+				if change, ok := changes[e.CompletedTurns]; ok {
+					commands <- gol.SetThreads{Threads: change}
+					threads = change
+				}
+			case gol.FinalTurnComplete:
+				assertEqualBoard(t, e.Alive, expectedAlive, p)
+			}
+		}
+	}
+}
+
+// TestTurnStats tests that a TurnStats event with a duration for every worker is sent just before each TurnComplete
+func TestTurnStats(t *testing.T) {
+	p := gol.Params{ImageWidth: 16, ImageHeight: 16, Turns: 10, Threads: 4, TurnStats: true}
+	events := make(chan gol.Event)
+	go gol.Run(p, events, nil)
+	var last *gol.TurnStats
+	turns := 0
+	for event := range events {
+		switch e := event.(type) {
+		case gol.TurnStats:
+			if len(e.Workers) != p.Threads {
+				t.Errorf("expected %v worker durations, got %v", p.Threads, len(e.Workers))
+			}
+			slowest, fastest := e.Workers[0], e.Workers[0]
+			for _, duration := range e.Workers {
+				if duration > slowest {
+					slowest = duration
+				}
+				if duration < fastest {
+					fastest = duration
+				}
+			}
+			if e.Imbalance != slowest-fastest {
+				t.Errorf("expected an imbalance of %v, got %v", slowest-fastest, e.Imbalance)
+			}
+			if e.Step < slowest {
+				t.Errorf("step of %v was shorter than the slowest worker's %v", e.Step, slowest)
+			}
+			last = &e
+		case gol.TurnComplete:
+			if last == nil || last.CompletedTurns != e.CompletedTurns {
+				t.Fatalf("no TurnStats was sent before TurnComplete %v", e.CompletedTurns)
+			}
+			last = nil
+			turns++
+		}
+	}
+	if turns != p.Turns {
+		t.Errorf("expected %v turns, got %v", p.Turns, turns)
+	}
+}
+
+// TestUnbounded tests that the glider in the 16x16 image keeps going past the edges of an unbounded game instead of
+// wrapping around, and that the output image only holds the glider
+func TestUnbounded(t *testing.T) {
+	p := gol.Params{ImageWidth: 16, ImageHeight: 16, Turns: 100, Threads: 4, Unbounded: true}
+	var expected []util.Cell
+	for _, cell := range readAliveCells("check/images/16x16x0.pgm", p.ImageWidth, p.ImageHeight) {
+		expected = append(expected, util.Cell{X: cell.X + p.Turns/4, Y: cell.Y + p.Turns/4}) // down and right every 4 turns
+	}
+	events := make(chan gol.Event)
+	go gol.Run(p, events, nil)
+	for event := range events {
+		if e, ok := event.(gol.FinalTurnComplete); ok {
+			assertEqualBoard(t, e.Alive, expected, gol.Params{ImageWidth: 32, ImageHeight: 32})
+		}
+	}
+	var glider []util.Cell
+	for _, cell := range readAliveCells("out/3x3x100.pgm", 3, 3) {
+		glider = append(glider, util.Cell{X: cell.X + 3 + p.Turns/4, Y: cell.Y + 5 + p.Turns/4}) // the glider started at 3, 5
+	}
+	assertEqualBoard(t, glider, expected, p)
+
+	// another glider turned around goes up and left, into negative cells, whilst the first carries on as before
+	pattern, err := gol.LoadPattern("patterns/glider.rle")
+	util.Check(err)
+	commands := make(chan gol.Command, 1)
+	commands <- gol.StampPattern{Pattern: pattern, X: 0, Y: 0, Rotation: gol.Rotate180}
+	for _, cell := range pattern.Rotate(gol.Rotate180).Cells {
+		expected = append(expected, util.Cell{X: cell.X - p.Turns/4, Y: cell.Y - p.Turns/4})
+	}
+	events = make(chan gol.Event)
+	go gol.RunWithCommands(p, events, nil, commands)
+	for event := range events {
+		if e, ok := event.(gol.FinalTurnComplete); ok {
+			assertEqualBoard(t, e.Alive, expected, gol.Params{ImageWidth: 32, ImageHeight: 32})
+		}
+	}
+}
+
+// TestUnboundedBirth tests that rules giving birth in empty space are refused on an unbounded board, which they would
+// fill forever, but not on a bounded one
+func TestUnboundedBirth(t *testing.T) {
+	for _, rule := range []string{"B03/S23", "B0/S/C3"} {
+		p := gol.Params{ImageWidth: 16, ImageHeight: 16, Turns: 1, Threads: 4, Rule: rule}
+		if err := gol.CheckParams(p); err != nil {
+			t.Errorf("%v should be allowed on a bounded board, but got %v", rule, err)
+		}
+		p.Unbounded = true
+		if err := gol.CheckParams(p); err == nil {
+			t.Errorf("%v should not be allowed on an unbounded board", rule)
+		}
+	}
+	if err := gol.CheckParams(gol.Params{ImageWidth: 16, ImageHeight: 16, Unbounded: true, Rule: "B36/S23"}); err != nil {
+		t.Errorf("B36/S23 should be allowed on an unbounded board, but got %v", err)
+	}
+}
+
+// TestGenerations tests Brian's Brain, where alive cells spend a turn dying before they are dead, against states worked
+// out here one cell at a time. It checks the states sent in events and the grey levels in the output image.
+func TestGenerations(t *testing.T) {
+	p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 20, Threads: 4, Rule: "B2/S/C3"}
+	states := make([]uint8, p.ImageWidth*p.ImageHeight) // 0 is dead, 255 alive and 127 dying
+	for _, cell := range readAliveCells("check/images/64x64x0.pgm", p.ImageWidth, p.ImageHeight) {
+		states[cell.Y*p.ImageWidth+cell.X] = 255
+	}
+	for turn := 0; turn < p.Turns; turn++ {
+		next := make([]uint8, len(states))
+		for y := 0; y < p.ImageHeight; y++ {
+			for x := 0; x < p.ImageWidth; x++ {
+				neighbours := 0
+				for dy := -1; dy <= 1; dy++ {
+					for dx := -1; dx <= 1; dx++ {
+						if (dx != 0 || dy != 0) && states[(y+dy+p.ImageHeight)%p.ImageHeight*p.ImageWidth+(x+dx+p.ImageWidth)%p.ImageWidth] == 255 {
+							neighbours++
+						}
+					}
+				}
+				switch states[y*p.ImageWidth+x] {
+				case 0:
+					if neighbours == 2 {
+						next[y*p.ImageWidth+x] = 255
+					}
+				case 255:
+					next[y*p.ImageWidth+x] = 127
+				}
+			}
+		}
+		states = next
+	}
+
+	tracked := make([]uint8, len(states))
+	events := make(chan gol.Event)
+	go gol.Run(p, events, nil)
+	for event := range events {
+		switch e := event.(type) {
+		case gol.CellFlipped:
+			tracked[e.Cell.Y*p.ImageWidth+e.Cell.X] ^= 255
+		case gol.CellStateChanged:
+			tracked[e.Cell.Y*p.ImageWidth+e.Cell.X] = e.State
+		}
+	}
+	image, err := ioutil.ReadFile("out/64x64x20.pgm")
+	util.Check(err)
+	image = image[len(image)-len(states):] // skip the header
+	for i := range states {
+		if tracked[i] != states[i] || image[i] != states[i] {
+			t.Fatalf("cell (%d, %d) should be %d, but events gave %d and the image %d",
+				i%p.ImageWidth, i/p.ImageWidth, states[i], tracked[i], image[i])
+		}
+	}
+}
+
 func boardFail(t *testing.T, given, expected []util.Cell, p gol.Params) bool {
 	errorString := fmt.Sprintf("-----------------\n\n  FAILED TEST\n  %vx%v\n  %d Workers\n  %d Turns\n", p.ImageWidth, p.ImageHeight, p.Threads, p.Turns)
 	if p.ImageWidth == 16 && p.ImageHeight == 16 {
 		errorString = errorString + util.AliveCellsToString(given, expected, p.ImageWidth, p.ImageHeight)
+	} else { // too big to draw, so just what differs
+		errorString = errorString + "  " + diff.Compare(diff.FromCells(expected, p.ImageWidth, p.ImageHeight), diff.FromCells(given, p.ImageWidth, p.ImageHeight)).String() + "\n"
 	}
 	t.Error(errorString)
 	return false
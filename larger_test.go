@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestLargerThanLife tests Larger than Life rules with Moore and von Neumann neighbourhoods on the 64x64 image against
+// the images in check/ltl, which were worked out one cell at a time. Range 17 reaches past the tiles next to each tile.
+func TestLargerThanLife(t *testing.T) {
+	tests := []struct {
+		golden string
+		rule   string
+		turns  int
+	}{
+		{"moore", "R4,C0,M1,S30..55,B28..40,NM", 20},
+		{"vonneumann", "R2,C0,M0,S2..4,B3..3,NN", 20},
+		{"generations", "R3,C3,M0,S14..25,B14..19,NM", 20},
+		{"range17", "R17,C0,M1,S400..800,B500..700,NM", 10},
+	}
+	for _, test := range tests {
+		rule, err := gol.ParseRule(test.rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rule.String() != test.rule {
+			t.Errorf("%v was read back as %v", test.rule, rule)
+		}
+		expected, err := ioutil.ReadFile("check/ltl/" + test.golden + ".pgm")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, threads := range []int{1, 3, 8} {
+			p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: test.turns, Threads: threads, Rule: test.rule}
+			t.Run(fmt.Sprintf("%v-%d", test.golden, threads), func(t *testing.T) {
+				events := make(chan gol.Event)
+				go gol.Run(p, events, nil)
+				for range events {
+				}
+				output, err := ioutil.ReadFile(fmt.Sprintf("out/64x64x%d.pgm", p.Turns))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(output, expected) {
+					t.Errorf("%v after %d turns doesn't match check/ltl/%v.pgm", p.Rule, p.Turns, test.golden)
+				}
+			})
+		}
+	}
+}
+
+// TestLargeRange tests a range that only just fits on the board, where every neighbourhood wraps around the edges,
+// against neighbours counted one cell at a time here, and that ranges that don't fit are refused
+func TestLargeRange(t *testing.T) {
+	p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 1, Threads: 4, Rule: "R31,C0,M1,S2750..2850,B2700..2900,NM"}
+	alive := make(map[util.Cell]bool)
+	for _, cell := range readAliveCells("images/64x64.pgm", p.ImageWidth, p.ImageHeight) {
+		alive[cell] = true
+	}
+	for turn := 0; turn < p.Turns; turn++ {
+		next := make(map[util.Cell]bool)
+		for y := 0; y < p.ImageHeight; y++ {
+			for x := 0; x < p.ImageWidth; x++ {
+				count := 0
+				for dy := -31; dy <= 31; dy++ {
+					for dx := -31; dx <= 31; dx++ {
+						if alive[util.Cell{X: (x + dx + p.ImageWidth) % p.ImageWidth, Y: (y + dy + p.ImageHeight) % p.ImageHeight}] {
+							count++
+						}
+					}
+				}
+				cell := util.Cell{X: x, Y: y}
+				if alive[cell] && count >= 2750 && count <= 2850 || !alive[cell] && count >= 2700 && count <= 2900 {
+					next[cell] = true
+				}
+			}
+		}
+		alive = next
+	}
+	var expected []util.Cell
+	for cell := range alive {
+		expected = append(expected, cell)
+	}
+	assertEqualBoard(t, finalAlive(p), expected, p)
+
+	for _, rule := range []string{"R32,C0,M1,S2750..2850,B2700..2900,NM", "R40,C0,M0,S2..4,B3..3,NN"} {
+		if err := gol.CheckParams(gol.Params{ImageWidth: 64, ImageHeight: 64, Rule: rule}); err == nil {
+			t.Errorf("%v should not fit on a 64x64 board", rule)
+		}
+		if err := gol.CheckParams(gol.Params{ImageWidth: 64, ImageHeight: 64, Rule: rule, Unbounded: true}); err != nil {
+			t.Errorf("%v should be allowed on an unbounded board, but got %v", rule, err)
+		}
+	}
+}
@@ -3,14 +3,25 @@ package main
 import (
 	"flag"
 	"fmt"
+	"os"
 	"runtime"
+	"sync"
 
+	"uk.ac.bris.cs/gameoflife/eventlog"
 	"uk.ac.bris.cs/gameoflife/gol"
-	"uk.ac.bris.cs/gameoflife/sdl"
+	"uk.ac.bris.cs/gameoflife/metrics"
+	"uk.ac.bris.cs/gameoflife/record"
+	"uk.ac.bris.cs/gameoflife/server"
+	"uk.ac.bris.cs/gameoflife/terminal"
+	"uk.ac.bris.cs/gameoflife/util"
 )
 
 // main is the function called when starting Game of Life with 'go run .'
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		runDiff(os.Args[2:])
+		return
+	}
 	runtime.LockOSThread()
 	var params gol.Params
 
@@ -38,13 +49,134 @@ func main() {
 		10000000000,
 		"Specify the number of turns to process. Defaults to 10000000000.")
 
+	flag.BoolVar(
+		&params.BatchEvents,
+		"batch",
+		false,
+		"Sends the cells flipped by each worker as one event per turn instead of one event per cell.")
+
+	flag.BoolVar(
+		&params.TurnStats,
+		"turnStats",
+		false,
+		"Prints how long each turn and each worker took, and the imbalance between the slowest and fastest worker.")
+
+	flag.BoolVar(
+		&params.Unbounded,
+		"unbounded",
+		false,
+		"Lets the board grow forever instead of wrapping around the edges. Viewers show the area of the original image.")
+
+	flag.StringVar(
+		&params.Rule,
+		"rule",
+		"B3/S23",
+		"Specify the rule, e.g. B36/S23 for HighLife, B2/S/C3 for Brian's Brain where cells spend turns dying, R5,C0,M1,S34..58,B34..45,NM for Bosco's Rule, B2/S34H for hexagonal cells, 4555 for 3D Life with -depth, M0,8,4,3,2,5,9,7,1,6,10,11,12,13,14,15 for the Margolus billiard-ball machine, Immigration or QuadLife for cells with colours, or a Golly rule table such as rules/Wireworld.rule. Defaults to Conway's Game of Life.")
+
+	flag.Float64Var(
+		&params.Noise,
+		"noise",
+		0,
+		"Specify the probability of each cell doing the opposite of what the rule says on each turn. Defaults to 0.")
+
+	flag.Int64Var(
+		&params.Seed,
+		"seed",
+		0,
+		"Specify the seed for -noise. The same seed gives the same run with any number of threads. Defaults to 0.")
+
+	flag.IntVar(
+		&params.Depth,
+		"depth",
+		0,
+		"Specify the number of slices of a 3D board, for 3D rules such as 4555. The image holds the slices one under another.")
+
+	flag.IntVar(
+		&params.Slice,
+		"slice",
+		0,
+		"Specify the slice of a 3D board to show. Defaults to 0.")
+
 	noVis := flag.Bool(
 		"noVis",
 		false,
 		"Disables the SDL window, so there is no visualisation during the tests.")
 
+	term := flag.Bool(
+		"term",
+		false,
+		"Draws the board in the terminal instead of an SDL window.")
+
+	recordFormat := flag.String(
+		"record",
+		"",
+		"Records every turn without a window, either as numbered PNGs (png) or a raw greyscale frame stream (raw).")
+
+	recordPath := flag.String(
+		"recordTo",
+		"out/frames",
+		"Specify the directory for recorded PNGs, or the file for a raw stream where - means stdout. Defaults to out/frames.")
+
+	recordScale := flag.Int(
+		"recordScale",
+		1,
+		"Specify the number of pixels across each cell in recorded frames. Defaults to 1.")
+
+	serveAddr := flag.String(
+		"serve",
+		"",
+		"Serves a browser viewer and control API on this address, e.g. :8080, alongside any other viewer.")
+
+	eventLogPath := flag.String(
+		"eventLog",
+		"",
+		"Writes every event to this file as newline delimited JSON, so the run can be replayed later.")
+
+	replayPath := flag.String(
+		"replay",
+		"",
+		"Replays an event log written with -eventLog instead of running the game. The size and threads come from the log.")
+
+	replaySpeed := flag.Float64(
+		"replaySpeed",
+		1,
+		"Specify how many times faster than the original run to replay, or 0 for as fast as possible. Defaults to 1.")
+
+	metricsAddr := flag.String(
+		"metrics",
+		"",
+		"Serves Prometheus metrics at /metrics on this address, e.g. :9090.")
+
+	patternDir := flag.String(
+		"patterns",
+		"patterns",
+		"Specify the directory of .rle and .cells patterns that can be stamped onto the board. Defaults to patterns.")
+
 	flag.Parse()
 
+	_, err := gol.ParseRule(params.Rule)
+	util.Check(err)
+
+	var recordOptions record.Options
+	if *recordFormat != "" {
+		format, err := record.ParseFormat(*recordFormat)
+		util.Check(err)
+		recordOptions = record.Options{Format: format, Path: *recordPath, Scale: *recordScale}
+		if format == record.Raw && *recordPath == "-" {
+			os.Stdout = os.Stderr // keep everything else that is printed out of the frame stream
+		}
+	}
+
+	var replay *eventlog.Reader
+	if *replayPath != "" {
+		file, err := os.Open(*replayPath)
+		util.Check(err)
+		defer file.Close()
+		replay, err = eventlog.NewReader(file)
+		util.Check(err)
+		params = replay.Params
+	}
+
 	fmt.Println("Threads:", params.Threads)
 	fmt.Println("Width:", params.ImageWidth)
 	fmt.Println("Height:", params.ImageHeight)
@@ -52,17 +184,93 @@ func main() {
 	keyPresses := make(chan rune, 10)
 	events := make(chan gol.Event, 1000)
 
-	go gol.Run(params, events, keyPresses)
-	if !(*noVis) {
-		sdl.Run(params, events, keyPresses)
+	commands := make(chan gol.Command, 10)
+
+	patterns, err := gol.LoadPatternLibrary(*patternDir)
+	if err != nil {
+		fmt.Println("No patterns loaded:", err)
+	}
+
+	var gameMetrics *metrics.Metrics
+	if *metricsAddr != "" {
+		gameMetrics = metrics.New(func() int { return len(events) })
+		params.Hooks = gameMetrics.Hooks()
+		gameMetrics.Serve(*metricsAddr)
+	}
+
+	engineEvents := events
+	if params.Unbounded { // the viewers only draw the area of the original image
+		engineEvents = make(chan gol.Event, 1000)
+		go gol.Crop(engineEvents, events, params.ImageWidth, params.ImageHeight)
+	}
+	if replay != nil {
+		go eventlog.Replay(replay, engineEvents, keyPresses, *replaySpeed)
 	} else {
-		complete := false
-		for !complete {
-			event := <-events
-			switch event.(type) {
-			case gol.FinalTurnComplete:
-				complete = true
-			}
-		}
+		go gol.RunWithCommands(params, engineEvents, keyPresses, commands)
+	}
+
+	// every consumer subscribes to the bus for just the events it needs, so they can all run at once
+	bus := gol.NewBus()
+	var drained sync.WaitGroup // consumers that must see every event before exiting
+	if *eventLogPath != "" {
+		logged := bus.Subscribe(gol.AllEvents, 1000, gol.Block)
+		drained.Add(1)
+		go func() {
+			defer drained.Done()
+			eventlog.Run(*eventLogPath, params, logged.Events)
+		}()
+	}
+	if gameMetrics != nil {
+		counted := bus.Subscribe(gol.AliveCellsCountEvent|gol.FinalTurnCompleteEvent, 10, gol.DropOldest)
+		go gameMetrics.Run(counted.Events)
+	}
+	if params.TurnStats {
+		stats := bus.Subscribe(gol.TurnStatsEvent, 100, gol.DropNewest)
+		drained.Add(1)
+		go func() {
+			defer drained.Done()
+			printTurnStats(stats.Events)
+		}()
+	}
+	if *serveAddr != "" { // the browser viewer runs alongside whichever viewer is chosen below
+		served := bus.Subscribe(gol.AllEvents, 1000, gol.Block)
+		go server.Run(*serveAddr, params, served.Events, keyPresses)
+	}
+	var view func()
+	switch {
+	case *recordFormat != "":
+		recorded := bus.Subscribe(gol.AllEvents, 1000, gol.Block)
+		view = func() { record.Run(params, recorded.Events, recordOptions) }
+	case *term:
+		viewed := bus.Subscribe(gol.AllEvents, 1000, gol.Block)
+		view = func() { terminal.Run(params, viewed.Events, keyPresses) }
+	case !(*noVis):
+		viewed := bus.Subscribe(gol.BoardEvents, 1000, gol.Block)
+		logged := bus.Subscribe(gol.MessageEvents, 100, gol.DropNewest)
+		go logEvents(logged.Events)
+		view = func() { runSdl(params, viewed.Events, keyPresses, commands, patterns) }
+	default:
+		final := bus.Subscribe(gol.FinalTurnCompleteEvent, 1, gol.Block)
+		logged := bus.Subscribe(gol.MessageEvents, 100, gol.DropNewest)
+		go logEvents(logged.Events)
+		view = func() { <-final.Events }
+	}
+	go bus.Run(events)
+	view()
+	drained.Wait() // the log and stats are only complete once the engine has closed the events channel
+}
+
+// logEvents prints every event it is sent, in the same format as the SDL window
+func logEvents(events <-chan gol.Event) {
+	for event := range events {
+		fmt.Printf("Completed Turns %-8v%v\n", event.GetCompletedTurns(), event)
+	}
+}
+
+// printTurnStats prints the timings of every turn, with the workers in order
+func printTurnStats(events <-chan gol.Event) {
+	for event := range events {
+		stats := event.(gol.TurnStats)
+		fmt.Printf("Completed Turns %-8vStep %v Imbalance %v Workers %v\n", stats.CompletedTurns, stats.Step, stats.Imbalance, stats.Workers)
 	}
 }
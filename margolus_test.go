@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// advanceMargolus works out a turn of a Margolus rule one block at a time, with the blocks starting on even cells on
+// even turns and odd cells on odd turns
+func advanceMargolus(alive map[util.Cell]bool, blocks [16]int, turn, width, height int) map[util.Cell]bool {
+	next := make(map[util.Cell]bool)
+	corners := []util.Cell{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 0, Y: 1}, {X: 1, Y: 1}}
+	for y := turn % 2; y < height+turn%2; y += 2 {
+		for x := turn % 2; x < width+turn%2; x += 2 {
+			block := 0
+			for bit, corner := range corners {
+				if alive[util.Cell{X: (x + corner.X) % width, Y: (y + corner.Y) % height}] {
+					block |= 1 << uint(bit)
+				}
+			}
+			for bit, corner := range corners {
+				if blocks[block]&(1<<uint(bit)) != 0 {
+					next[util.Cell{X: (x + corner.X) % width, Y: (y + corner.Y) % height}] = true
+				}
+			}
+		}
+	}
+	return next
+}
+
+// TestMargolus tests Margolus rules against blocks worked out here, on the 64x64 image and on an empty board with a
+// few cells that stay still on even turns but not odd ones, with different numbers of workers
+func TestMargolus(t *testing.T) {
+	tests := []struct {
+		rule   string
+		blocks [16]int
+	}{
+		{"M0,8,4,3,2,5,9,7,1,6,10,11,12,13,14,15", [16]int{0, 8, 4, 3, 2, 5, 9, 7, 1, 6, 10, 11, 12, 13, 14, 15}},
+		{"M15,14,13,3,11,5,6,1,7,9,10,2,12,4,8,0", [16]int{15, 14, 13, 3, 11, 5, 6, 1, 7, 9, 10, 2, 12, 4, 8, 0}},
+	}
+	p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 30}
+	sparse := emptyBoardWith(gol.Pattern{Cells: []util.Cell{{X: 50, Y: 40}, {X: 51, Y: 40}, {X: 16, Y: 16}, {X: 31, Y: 15}, {X: 32, Y: 14}, {X: 32, Y: 15}}}, p, 0, 0)
+	for _, test := range tests {
+		rule, err := gol.ParseRule(test.rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rule.String() != test.rule {
+			t.Errorf("%v was read back as %v", test.rule, rule)
+		}
+		p.Rule = test.rule
+		for _, start := range []string{"image", "sparse"} {
+			alive := make(map[util.Cell]bool)
+			if start == "image" {
+				for _, cell := range readAliveCells("images/64x64.pgm", p.ImageWidth, p.ImageHeight) {
+					alive[cell] = true
+				}
+			} else {
+				for _, cell := range sparse.Cells {
+					alive[cell] = true
+				}
+			}
+			for turn := 0; turn < p.Turns; turn++ {
+				alive = advanceMargolus(alive, test.blocks, turn, p.ImageWidth, p.ImageHeight)
+			}
+			var expected []util.Cell
+			for cell := range alive {
+				expected = append(expected, cell)
+			}
+
+			for _, threads := range []int{1, 3, 8} {
+				p.Threads = threads
+				t.Run(fmt.Sprintf("%v-%v-%d", test.rule, start, threads), func(t *testing.T) {
+					commands := make(chan gol.Command, 1)
+					if start == "sparse" {
+						commands <- gol.StampPattern{Pattern: sparse}
+					}
+					events := make(chan gol.Event)
+					go gol.RunWithCommands(p, events, nil, commands)
+					for event := range events {
+						if e, ok := event.(gol.FinalTurnComplete); ok {
+							assertEqualBoard(t, e.Alive, expected, p)
+						}
+					}
+				})
+			}
+		}
+	}
+
+	for _, test := range tests { // only rules that leave empty blocks empty can be used on an unbounded board
+		unbounded := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 1, Unbounded: true, Rule: test.rule}
+		if err := gol.CheckParams(unbounded); (err == nil) != (test.blocks[0] == 0) {
+			t.Errorf("%v on an unbounded board gave %v", test.rule, err)
+		}
+	}
+
+	for _, rulestring := range []string{"M0,8,4,3,2,5,9,7,1,6,10,11,12,13,14", "M0,8,4,3,2,5,9,7,1,6,10,11,12,13,14,16"} {
+		if _, err := gol.ParseRule(rulestring); err == nil {
+			t.Errorf("%v should not be a valid Margolus rule", rulestring)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// buckets are the upper bounds, in seconds, of every latency histogram
+var buckets = []float64{0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+// histogram counts durations into buckets, in the same way as a Prometheus histogram
+type histogram struct {
+	counts []uint64 // counts[i] is the number of durations no longer than buckets[i]
+	sum    float64
+	count  uint64
+}
+
+func newHistogram() *histogram {
+	return &histogram{counts: make([]uint64, len(buckets))}
+}
+
+// observe adds one duration to the histogram
+func (h *histogram) observe(duration time.Duration) {
+	seconds := duration.Seconds()
+	for i, bound := range buckets {
+		if seconds <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+// write writes the histogram's samples, where labels is either empty or ends with a comma
+func (h *histogram) write(w io.Writer, name string, labels string) {
+	for i, bound := range buckets {
+		fmt.Fprintf(w, "%s_bucket{%sle=\"%v\"} %v\n", name, labels, bound, h.counts[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{%sle=\"+Inf\"} %v\n", name, labels, h.count)
+	if labels != "" {
+		labels = "{" + labels[:len(labels)-1] + "}"
+	}
+	fmt.Fprintf(w, "%s_sum%s %v\n", name, labels, h.sum)
+	fmt.Fprintf(w, "%s_count%s %v\n", name, labels, h.count)
+}
+
+// Metrics collects measurements of a running game and serves them in the Prometheus text format
+type Metrics struct {
+	lock           sync.Mutex
+	backlog        func() int // the number of events waiting to be consumed
+	completedTurns int
+	population     int
+	rateStart      time.Time // the start of the window turns per second is measured over
+	rateTurns      int       // completed turns at the start of the window
+	turnsPerSecond float64
+	turnSeconds    *histogram
+	workerSeconds  map[int]*histogram
+	imageSeconds   *histogram
+}
+
+// New creates Metrics for a game, where backlog returns the number of events waiting on the engine's events channel
+func New(backlog func() int) *Metrics {
+	return &Metrics{
+		backlog:       backlog,
+		rateStart:     time.Now(),
+		turnSeconds:   newHistogram(),
+		workerSeconds: make(map[int]*histogram),
+		imageSeconds:  newHistogram(),
+	}
+}
+
+// Hooks returns the hooks to set in gol.Params so the engine reports to these Metrics
+func (m *Metrics) Hooks() *gol.Hooks {
+	return &gol.Hooks{
+		TurnComplete:   m.turnComplete,
+		WorkerComplete: m.workerComplete,
+		ImageOutput:    m.imageOutput,
+	}
+}
+
+func (m *Metrics) turnComplete(completedTurns int, duration time.Duration) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.completedTurns = completedTurns
+	m.turnSeconds.observe(duration)
+	if elapsed := time.Since(m.rateStart); elapsed >= time.Second { // start a new window every second
+		m.turnsPerSecond = float64(completedTurns-m.rateTurns) / elapsed.Seconds()
+		m.rateStart = time.Now()
+		m.rateTurns = completedTurns
+	}
+}
+
+func (m *Metrics) workerComplete(worker int, duration time.Duration) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	h, ok := m.workerSeconds[worker]
+	if !ok {
+		h = newHistogram()
+		m.workerSeconds[worker] = h
+	}
+	h.observe(duration)
+}
+
+func (m *Metrics) imageOutput(duration time.Duration) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.imageSeconds.observe(duration)
+}
+
+// Run follows AliveCellsCount and FinalTurnComplete events to keep the population up to date,
+// so it is only as fresh as the last AliveCellsCount
+func (m *Metrics) Run(events <-chan gol.Event) {
+	for event := range events {
+		m.lock.Lock()
+		switch e := event.(type) {
+		case gol.AliveCellsCount:
+			m.population = e.CellsCount
+		case gol.FinalTurnComplete:
+			m.population = len(e.Alive)
+		}
+		m.lock.Unlock()
+	}
+}
+
+// Write writes every metric in the Prometheus text format
+func (m *Metrics) Write(w io.Writer) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	turnsPerSecond := m.turnsPerSecond
+	if elapsed := time.Since(m.rateStart); elapsed >= 2*time.Second { // no turn has finished the window, e.g. when paused
+		turnsPerSecond = float64(m.completedTurns-m.rateTurns) / elapsed.Seconds()
+	}
+
+	metric := func(name, kind, help string) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+	metric("gol_turns_completed_total", "counter", "Number of turns completed.")
+	fmt.Fprintf(w, "gol_turns_completed_total %v\n", m.completedTurns)
+	metric("gol_turns_per_second", "gauge", "Turns completed per second, measured over about a second.")
+	fmt.Fprintf(w, "gol_turns_per_second %v\n", turnsPerSecond)
+	metric("gol_alive_cells", "gauge", "Number of alive cells, as of the last AliveCellsCount event.")
+	fmt.Fprintf(w, "gol_alive_cells %v\n", m.population)
+	if m.backlog != nil {
+		metric("gol_event_backlog", "gauge", "Number of events waiting to be consumed.")
+		fmt.Fprintf(w, "gol_event_backlog %v\n", m.backlog())
+	}
+	metric("gol_turn_duration_seconds", "histogram", "Time taken to advance the whole board one turn.")
+	m.turnSeconds.write(w, "gol_turn_duration_seconds", "")
+	metric("gol_worker_step_duration_seconds", "histogram", "Time taken by each worker to advance its section of the board one turn.")
+	workers := make([]int, 0, len(m.workerSeconds))
+	for worker := range m.workerSeconds {
+		workers = append(workers, worker)
+	}
+	sort.Ints(workers)
+	for _, worker := range workers {
+		m.workerSeconds[worker].write(w, "gol_worker_step_duration_seconds", fmt.Sprintf("worker=\"%v\",", worker))
+	}
+	metric("gol_snapshot_duration_seconds", "histogram", "Time taken to output an image of the board.")
+	m.imageSeconds.write(w, "gol_snapshot_duration_seconds", "")
+}
+
+// ServeHTTP serves every metric, so Metrics can be scraped by Prometheus
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	m.Write(w)
+}
+
+// Serve serves the metrics on addr at /metrics in the background
+func (m *Metrics) Serve(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m)
+	go func() {
+		util.Check(http.ListenAndServe(addr, mux))
+	}()
+	fmt.Println("Serving metrics on", addr)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/metrics"
+)
+
+// TestMetrics tests that the hooks count every turn, every worker and the final image of a 16x16 image over 10 turns
+func TestMetrics(t *testing.T) {
+	gameMetrics := metrics.New(nil)
+	p := gol.Params{ImageWidth: 16, ImageHeight: 16, Turns: 10, Threads: 4, Hooks: gameMetrics.Hooks()}
+	events := make(chan gol.Event)
+	go gol.Run(p, events, nil)
+	counted := make(chan gol.Event)
+	done := make(chan struct{})
+	go func() {
+		gameMetrics.Run(counted)
+		close(done)
+	}()
+	alive := 0
+	for event := range events {
+		if final, ok := event.(gol.FinalTurnComplete); ok {
+			alive = len(final.Alive)
+		}
+		counted <- event
+	}
+	close(counted)
+	<-done
+
+	var scraped bytes.Buffer
+	gameMetrics.Write(&scraped)
+	for _, line := range []string{
+		"gol_turns_completed_total 10",
+		fmt.Sprintf("gol_alive_cells %v", alive),
+		"gol_turn_duration_seconds_count 10",
+		`gol_worker_step_duration_seconds_count{worker="0"} 10`,
+		`gol_worker_step_duration_seconds_count{worker="3"} 10`,
+		"gol_snapshot_duration_seconds_count 1",
+	} {
+		if !strings.Contains(scraped.String(), line+"\n") {
+			t.Errorf("expected the line %q in\n%v", line, scraped.String())
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"testing"
 	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
 )
 
 const benchLength = 100
@@ -36,3 +37,74 @@ func BenchmarkGol(b *testing.B) {
 		})
 	}
 }
+
+// BenchmarkEvents compares one CellFlipped per cell against one CellsFlipped per tile,
+// both consumed directly and through the Unbatch adapter.
+func BenchmarkEvents(b *testing.B) {
+	os.Stdout = nil // Disable all program output apart from benchmark results
+	for _, mode := range []string{"cell", "batched", "adapter"} {
+		p := gol.Params{
+			Turns:       benchLength,
+			Threads:     8,
+			ImageWidth:  512,
+			ImageHeight: 512,
+			BatchEvents: mode != "cell",
+		}
+		b.Run(mode, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				events := make(chan gol.Event, 1000)
+				go gol.Run(p, events, nil)
+				var consumed <-chan gol.Event = events
+				if mode == "adapter" {
+					unbatched := make(chan gol.Event, 1000)
+					go gol.Unbatch(events, unbatched)
+					consumed = unbatched
+				}
+				flipped := 0
+				for event := range consumed {
+					switch e := event.(type) {
+					case gol.CellFlipped:
+						flipped++
+					case gol.CellsFlipped:
+						flipped += len(e.Cells)
+					}
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkSparse compares the 512x512 image against the same board emptied apart from one small pattern,
+// where only the tiles around the pattern need advancing.
+func BenchmarkSparse(b *testing.B) {
+	os.Stdout = nil // Disable all program output apart from benchmark results
+	for _, name := range []string{"dense", "glider", "r-pentomino"} {
+		p := gol.Params{
+			Turns:       benchLength,
+			Threads:     8,
+			ImageWidth:  512,
+			ImageHeight: 512,
+		}
+		var board gol.Pattern
+		if name != "dense" {
+			pattern, err := gol.LoadPattern("patterns/" + name + ".rle")
+			util.Check(err)
+			board = gol.Pattern{Name: name, Width: 512, Height: 512}
+			for _, cell := range pattern.Cells {
+				board.Cells = append(board.Cells, util.Cell{X: cell.X + 256, Y: cell.Y + 256})
+			}
+		}
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				commands := make(chan gol.Command, 1)
+				if name != "dense" {
+					commands <- gol.StampPattern{Pattern: board}
+				}
+				events := make(chan gol.Event, 1000)
+				go gol.RunWithCommands(p, events, nil, commands)
+				for range events {
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// finalAlive runs a game and returns the alive cells at the end
+func finalAlive(p gol.Params) []util.Cell {
+	events := make(chan gol.Event)
+	go gol.Run(p, events, nil)
+	var alive []util.Cell
+	for event := range events {
+		if e, ok := event.(gol.FinalTurnComplete); ok {
+			alive = e.Alive
+		}
+	}
+	return alive
+}
+
+// TestNoise tests that noise gives the same board for the same seed whatever the number of threads, a different board
+// for a different seed, and that noise of 1 makes every cell do the opposite of what Conway's Game of Life says.
+func TestNoise(t *testing.T) {
+	p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 50, Noise: 0.01, Seed: 42}
+	var expected []util.Cell
+	for _, threads := range []int{1, 3, 8} {
+		p.Threads = threads
+		t.Run(fmt.Sprintf("%d threads", threads), func(t *testing.T) {
+			alive := finalAlive(p)
+			if expected == nil {
+				expected = alive
+				return
+			}
+			assertEqualBoard(t, alive, expected, p)
+		})
+	}
+
+	p.Seed = 43
+	alive := make(map[util.Cell]bool)
+	for _, cell := range finalAlive(p) {
+		alive[cell] = true
+	}
+	same := len(alive) == len(expected)
+	for _, cell := range expected {
+		same = same && alive[cell]
+	}
+	if same {
+		t.Error("noise with a different seed should give a different board")
+	}
+
+	// with nothing born or surviving, the board after a turn is just the cells the noise changed on that turn, which
+	// shouldn't be the same for nearby seeds on nearby turns
+	noiseOn := func(seed int64, turn int) map[util.Cell]bool {
+		hits := make(map[util.Cell]bool)
+		for _, cell := range finalAlive(gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: turn + 1, Threads: 4, Rule: "B/S", Noise: 0.1, Seed: seed}) {
+			hits[cell] = true
+		}
+		return hits
+	}
+	for _, key := range []struct {
+		seed int64
+		turn int
+	}{{42, 0}, {42, 1}, {7, 4}} {
+		first, second := noiseOn(key.seed, key.turn), noiseOn(key.seed^1, key.turn^1)
+		same := len(first) == len(second)
+		for cell := range first {
+			same = same && second[cell]
+		}
+		if same {
+			t.Errorf("seed %d on turn %d should have different noise to seed %d on turn %d", key.seed, key.turn, key.seed^1, key.turn^1)
+		}
+	}
+
+	p = gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 1, Threads: 4, Noise: 1}
+	notAlive := make(map[util.Cell]bool)
+	for _, cell := range readAliveCells("check/images/64x64x1.pgm", p.ImageWidth, p.ImageHeight) {
+		notAlive[cell] = true
+	}
+	var inverted []util.Cell
+	for y := 0; y < p.ImageHeight; y++ {
+		for x := 0; x < p.ImageWidth; x++ {
+			if !notAlive[util.Cell{X: x, Y: y}] {
+				inverted = append(inverted, util.Cell{X: x, Y: y})
+			}
+		}
+	}
+	assertEqualBoard(t, finalAlive(p), inverted, p)
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestPatternLibrary checks the patterns in the library parse with the right sizes and cell counts.
+func TestPatternLibrary(t *testing.T) {
+	patterns, err := gol.LoadPatternLibrary("patterns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][3]int{ // width, height, alive cells
+		"Glider":            {3, 3, 5},
+		"Gosper glider gun": {36, 9, 36},
+		"R-pentomino":       {3, 3, 5},
+		"LWSS":              {5, 4, 9},
+		"Acorn":             {7, 3, 7},
+	}
+	for _, pattern := range patterns {
+		size, ok := expected[pattern.Name]
+		if !ok {
+			continue
+		}
+		delete(expected, pattern.Name)
+		if pattern.Width != size[0] || pattern.Height != size[1] || len(pattern.Cells) != size[2] {
+			t.Errorf("%v: expected %dx%d with %d cells, got %dx%d with %d cells",
+				pattern.Name, size[0], size[1], size[2], pattern.Width, pattern.Height, len(pattern.Cells))
+		}
+		rotated := pattern.Rotate(gol.Rotate90)
+		if rotated.Width != pattern.Height || rotated.Height != pattern.Width {
+			t.Errorf("%v: rotating by 90 degrees should swap the width and height", pattern.Name)
+		}
+		if fmt.Sprint(rotated.Rotate(gol.Rotate270).Cells) != fmt.Sprint(pattern.Cells) {
+			t.Errorf("%v: rotating by 360 degrees should give back the same pattern", pattern.Name)
+		}
+	}
+	for name := range expected {
+		t.Errorf("%v is missing from the pattern library", name)
+	}
+}
+
+// TestStamp stamps a glider turned by 180 degrees across the wrap-around edge of a 16x16 board, and checks that the
+// final board and the board built from CellFlipped events both match the stamped image advanced one cell at a time.
+func TestStamp(t *testing.T) {
+	p := gol.Params{ImageWidth: 16, ImageHeight: 16, Turns: 10, Threads: 4}
+	glider, err := gol.LoadPattern("patterns/glider.rle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated := make(map[util.Cell]bool)
+	for _, cell := range glider.Rotate(gol.Rotate90).Cells {
+		rotated[cell] = true
+	}
+	clockwise := map[util.Cell]bool{{X: 0, Y: 0}: true, {X: 0, Y: 1}: true, {X: 2, Y: 1}: true, {X: 0, Y: 2}: true, {X: 1, Y: 2}: true}
+	if !reflect.DeepEqual(rotated, clockwise) {
+		t.Errorf("a glider turned by 90 degrees should have cells %v, not %v", clockwise, rotated)
+	}
+
+	alive := make(map[util.Cell]bool)
+	for _, cell := range readAliveCells("check/images/16x16x0.pgm", p.ImageWidth, p.ImageHeight) {
+		alive[cell] = true
+	}
+	for y := 14; y < 17; y++ { // the stamp overwrites its 3x3 box, which wraps onto the first column and row
+		for x := 14; x < 17; x++ {
+			delete(alive, util.Cell{X: x % 16, Y: y % 16})
+		}
+	}
+	for _, cell := range []util.Cell{{X: 14, Y: 14}, {X: 15, Y: 14}, {X: 0, Y: 14}, {X: 14, Y: 15}, {X: 15, Y: 0}} {
+		alive[cell] = true // the glider turned upside down
+	}
+	for turn := 0; turn < p.Turns; turn++ {
+		alive = advanceEveryCell(alive, p.ImageWidth, p.ImageHeight)
+	}
+	var expected []util.Cell
+	for cell := range alive {
+		expected = append(expected, cell)
+	}
+
+	commands := make(chan gol.Command, 1)
+	commands <- gol.StampPattern{Pattern: glider, X: 14, Y: 14, Rotation: gol.Rotate180}
+	events := make(chan gol.Event)
+	go gol.RunWithCommands(p, events, nil, commands)
+
+	board := make(map[util.Cell]bool)
+	for event := range events {
+		switch e := event.(type) {
+		case gol.CellFlipped:
+			board[e.Cell] = !board[e.Cell]
+		case gol.FinalTurnComplete:
+			var flipped []util.Cell
+			for cell, alive := range board {
+				if alive {
+					flipped = append(flipped, cell)
+				}
+			}
+			assertEqualBoard(t, e.Alive, expected, p)
+			assertEqualBoard(t, flipped, expected, p)
+		}
+	}
+}
+
+// TestSparseBoard tests that a glider on an otherwise empty 128x128 board, where most tiles are skipped, moves across
+// the tiles and wraps around the edges. The board is emptied by stamping a 128x128 pattern with only the glider and a
+// pair of cells in it, which die straight away and leave a tile to be skipped whilst it still has cells on the other board.
+func TestSparseBoard(t *testing.T) {
+	p := gol.Params{ImageWidth: 128, ImageHeight: 128, Turns: 40, Threads: 4}
+	glider, err := gol.LoadPattern("patterns/glider.rle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cells := append([]util.Cell{{X: 68, Y: 68}, {X: 69, Y: 68}}, glider.Cells...)
+	board := gol.Pattern{Name: "sparse", Width: 128, Height: 128, Cells: cells}
+	commands := make(chan gol.Command, 1)
+	commands <- gol.StampPattern{Pattern: board, X: 120, Y: 120}
+	events := make(chan gol.Event)
+	go gol.RunWithCommands(p, events, nil, commands)
+
+	var expected []util.Cell
+	for _, cell := range glider.Cells { // the glider moves one cell down and right every 4 turns
+		expected = append(expected, util.Cell{X: (cell.X + 120 + p.Turns/4) % 128, Y: (cell.Y + 120 + p.Turns/4) % 128})
+	}
+	for event := range events {
+		if e, ok := event.(gol.FinalTurnComplete); ok {
+			assertEqualBoard(t, e.Alive, expected, p)
+		}
+	}
+}
+
+// TestActiveTiles tests that only advancing the tiles around changes gives exactly the same board as advancing every
+// cell, using a 128x128 board that is emptied apart from an R-pentomino, which spreads out and leaves still lifes,
+// oscillators and gliders behind. The expected board is worked out here one cell at a time.
+func TestActiveTiles(t *testing.T) {
+	p := gol.Params{ImageWidth: 128, ImageHeight: 128, Turns: 300, Threads: 3}
+	pentomino, err := gol.LoadPattern("patterns/r-pentomino.rle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	board := gol.Pattern{Name: "pentomino", Width: 128, Height: 128}
+	alive := make(map[util.Cell]bool)
+	for _, cell := range pentomino.Cells {
+		board.Cells = append(board.Cells, util.Cell{X: cell.X + 62, Y: cell.Y + 62})
+		alive[util.Cell{X: cell.X + 62, Y: cell.Y + 62}] = true
+	}
+	commands := make(chan gol.Command, 1)
+	commands <- gol.StampPattern{Pattern: board}
+	events := make(chan gol.Event)
+	go gol.RunWithCommands(p, events, nil, commands)
+
+	for turn := 0; turn < p.Turns; turn++ {
+		alive = advanceEveryCell(alive, p.ImageWidth, p.ImageHeight)
+	}
+	var expected []util.Cell
+	for cell := range alive {
+		expected = append(expected, cell)
+	}
+	for event := range events {
+		if e, ok := event.(gol.FinalTurnComplete); ok {
+			assertEqualBoard(t, e.Alive, expected, p)
+		}
+	}
+}
+
+// advanceEveryCell advances a board one turn by checking the neighbours of every cell, accounting for wrap around
+func advanceEveryCell(alive map[util.Cell]bool, width, height int) map[util.Cell]bool {
+	advanced := make(map[util.Cell]bool)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			neighbours := 0
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					if (dx != 0 || dy != 0) && alive[util.Cell{X: (x + dx + width) % width, Y: (y + dy + height) % height}] {
+						neighbours++
+					}
+				}
+			}
+			cell := util.Cell{X: x, Y: y}
+			if neighbours == 3 || neighbours == 2 && alive[cell] {
+				advanced[cell] = true
+			}
+		}
+	}
+	return advanced
+}
@@ -2,8 +2,13 @@ package main
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
 	"testing"
+
 	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
 )
 
 // Pgm tests 16x16, 64x64 and 512x512 image output files on 0, 1 and 100 turns using 1-16 worker threads.
@@ -40,3 +45,38 @@ func TestPgm(t *testing.T) {
 		}
 	}
 }
+
+// TestPgmShades tests that saved images of Generations rules can be loaded again when the grey levels of their dying
+// states are whitespace characters, which are 11 for the last of 24 states and 10 for the last of 26
+func TestPgmShades(t *testing.T) {
+	p := gol.Params{ImageWidth: 20, ImageHeight: 20, Threads: 4}
+	path := fmt.Sprintf("images/%vx%v.pgm", p.ImageWidth, p.ImageHeight)
+	defer os.Remove(path)
+	for rule, dying := range map[string]uint8{"B2/S/C24": 11, "B2/S/C26": 10} {
+		p.Rule = rule
+		pixels := make([]byte, p.ImageWidth*p.ImageHeight)
+		expected := make(map[util.Cell]uint8)
+		for i := range pixels {
+			switch i % 7 {
+			case 0:
+				pixels[i] = dying
+			case 3:
+				pixels[i] = 255
+			}
+			if pixels[i] != 0 {
+				expected[util.Cell{X: i % p.ImageWidth, Y: i / p.ImageWidth}] = pixels[i]
+			}
+		}
+		header := fmt.Sprintf("P5\n%v %v\n255\n", p.ImageWidth, p.ImageHeight)
+		if err := ioutil.WriteFile(path, append([]byte(header), pixels...), 0644); err != nil {
+			t.Fatal(err)
+		}
+		events := make(chan gol.Event)
+		go gol.Run(p, events, nil)
+		for range events {
+		}
+		if shades := readShades(fmt.Sprintf("out/%vx%vx0.pgm", p.ImageWidth, p.ImageHeight), p.ImageWidth, p.ImageHeight); !reflect.DeepEqual(shades, expected) {
+			t.Errorf("%v: the image with dying cells of %d wasn't loaded as it was saved", rule, dying)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package record
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// Format chooses how frames are written
+type Format int
+
+const (
+	PNG Format = iota // one numbered greyscale PNG per turn in a directory, or with the palette of a rule with colours
+	Raw               // a stream of 8 bit greyscale frames, e.g. for ffmpeg -f rawvideo -pix_fmt gray
+)
+
+// Options describes where and how frames are recorded
+type Options struct {
+	Format Format
+	Path   string // the directory for PNGs, or the file for a raw stream where "-" means stdout
+	Scale  int    // the number of pixels across each cell, so small boards make usable videos
+}
+
+// ParseFormat converts the name of a format into a Format
+func ParseFormat(name string) (Format, error) {
+	switch name {
+	case "png":
+		return PNG, nil
+	case "raw":
+		return Raw, nil
+	default:
+		return PNG, fmt.Errorf("unknown record format %q, expected png or raw", name)
+	}
+}
+
+// Run follows the same events as sdl.Run, but writes a frame of the loaded image, once the game starts executing,
+// and then a frame for every TurnComplete instead of drawing a window.
+func Run(p gol.Params, events <-chan gol.Event, options Options) {
+	if options.Scale < 1 {
+		options.Scale = 1
+	}
+	rule, err := gol.ParseRule(p.Rule)
+	util.Check(err)
+	board := make([]byte, p.ImageWidth*p.ImageHeight)
+	frame := image.NewGray(image.Rect(0, 0, p.ImageWidth*options.Scale, p.ImageHeight*options.Scale))
+
+	var stream *bufio.Writer
+	switch options.Format {
+	case PNG:
+		util.Check(os.MkdirAll(options.Path, os.ModePerm))
+	case Raw:
+		// stdout is opened by its file descriptor, as main points os.Stdout at stderr to keep messages out of the stream
+		var file io.Writer = os.NewFile(uintptr(syscall.Stdout), "/dev/stdout")
+		if options.Path != "-" {
+			created, err := os.Create(options.Path)
+			util.Check(err)
+			defer created.Close()
+			file = created
+		}
+		stream = bufio.NewWriter(file)
+		defer func() {
+			util.Check(stream.Flush())
+		}()
+	}
+
+	write := func(turn int) {
+		drawFrame(frame, board, p.ImageWidth, options.Scale)
+		if options.Format == Raw {
+			_, err := stream.Write(frame.Pix)
+			util.Check(err)
+		} else if rule.Colours > 0 {
+			writePng(filepath.Join(options.Path, fmt.Sprintf("%06d.png", turn)), paletted(frame, rule))
+		} else {
+			writePng(filepath.Join(options.Path, fmt.Sprintf("%06d.png", turn)), frame)
+		}
+	}
+
+	loaded := false
+	for event := range events {
+		switch e := event.(type) {
+		case gol.CellFlipped:
+			board[e.Cell.Y*p.ImageWidth+e.Cell.X] = ^board[e.Cell.Y*p.ImageWidth+e.Cell.X]
+		case gol.CellsFlipped:
+			for _, cell := range e.Cells {
+				board[cell.Y*p.ImageWidth+cell.X] = ^board[cell.Y*p.ImageWidth+cell.X]
+			}
+		case gol.CellStateChanged:
+			board[e.Cell.Y*p.ImageWidth+e.Cell.X] = e.State
+		case gol.StateChange:
+			if !loaded && e.NewState == gol.Executing && e.CompletedTurns == 0 { // the game is starting
+				loaded = true
+				write(0)
+			}
+			fmt.Fprintf(os.Stderr, "Completed Turns %-8v%v\n", event.GetCompletedTurns(), event)
+		case gol.TurnComplete:
+			write(e.CompletedTurns)
+		case gol.FinalTurnComplete:
+			return
+		default:
+			if len(event.String()) > 0 {
+				fmt.Fprintf(os.Stderr, "Completed Turns %-8v%v\n", event.GetCompletedTurns(), event)
+			}
+		}
+	}
+}
+
+// drawFrame copies the board into the frame, scaling each cell up to a square of pixels
+func drawFrame(frame *image.Gray, board []byte, width int, scale int) {
+	for y := 0; y < frame.Rect.Dy(); y++ {
+		row := board[(y/scale)*width:]
+		pixels := frame.Pix[y*frame.Stride:]
+		for x := 0; x < frame.Rect.Dx(); x++ {
+			pixels[x] = row[x/scale]
+		}
+	}
+}
+
+// paletted converts a frame of grey levels into the colours of a rule with colours
+func paletted(frame *image.Gray, rule gol.Rule) *image.Paletted {
+	coloured := image.NewPaletted(frame.Rect, rule.Palette())
+	for i, shade := range frame.Pix {
+		coloured.Pix[i] = rule.PaletteIndex(shade)
+	}
+	return coloured
+}
+
+// writePng saves one frame as a PNG file
+func writePng(path string, frame image.Image) {
+	file, err := os.Create(path)
+	util.Check(err)
+	defer file.Close()
+	util.Check(png.Encode(file, frame))
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/record"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestRecord tests that a small game is recorded as a scaled up PNG of the image and of every turn, and as a raw
+// stream with the same frames
+func TestRecord(t *testing.T) {
+	dir, err := ioutil.TempDir("", "frames")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	p := gol.Params{ImageWidth: 16, ImageHeight: 16, Turns: 4, Threads: 2}
+	scale := 3
+	recordRun := func(options record.Options) {
+		events := make(chan gol.Event)
+		go gol.Run(p, events, nil)
+		record.Run(p, events, options)
+		for range events { // the game carries on sending events after the final turn
+		}
+	}
+
+	recordRun(record.Options{Format: record.PNG, Path: filepath.Join(dir, "png"), Scale: scale})
+	recordRun(record.Options{Format: record.Raw, Path: filepath.Join(dir, "frames.raw"), Scale: scale})
+	raw, err := ioutil.ReadFile(filepath.Join(dir, "frames.raw"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	frameSize := p.ImageWidth * p.ImageHeight * scale * scale
+	if len(raw) != (p.Turns+1)*frameSize {
+		t.Errorf("the raw stream should have %d frames of %d bytes, not %d bytes", p.Turns+1, frameSize, len(raw))
+	}
+
+	alive := make(map[util.Cell]bool)
+	for _, cell := range readAliveCells("check/images/16x16x0.pgm", p.ImageWidth, p.ImageHeight) {
+		alive[cell] = true
+	}
+	for turn := 0; turn <= p.Turns; turn++ {
+		file, err := os.Open(filepath.Join(dir, "png", fmt.Sprintf("%06d.png", turn)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		frame, err := png.Decode(file)
+		file.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if size := frame.Bounds().Size(); size.X != p.ImageWidth*scale || size.Y != p.ImageHeight*scale {
+			t.Fatalf("frame %d should be %dx%d, not %v", turn, p.ImageWidth*scale, p.ImageHeight*scale, size)
+		}
+		wrong := 0
+		for y := 0; y < p.ImageHeight*scale; y++ {
+			for x := 0; x < p.ImageWidth*scale; x++ {
+				var expected uint8
+				if alive[util.Cell{X: x / scale, Y: y / scale}] {
+					expected = 255
+				}
+				grey, _, _, _ := frame.At(x, y).RGBA()
+				if uint8(grey>>8) != expected || raw[turn*frameSize+y*p.ImageWidth*scale+x] != expected {
+					wrong++
+				}
+			}
+		}
+		if wrong > 0 {
+			t.Errorf("%d pixels of frame %d are wrong", wrong, turn)
+		}
+		alive = advanceEveryCell(alive, p.ImageWidth, p.ImageHeight)
+	}
+}
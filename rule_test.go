@@ -0,0 +1,192 @@
+package main
+
+import (
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestHensel tests that isotropic non-totalistic rules are read and written back in Hensel notation, and runs B2-a/S12
+// on the 64x64 image against the rule worked out here from where the alive neighbours are: a dead cell is born with 2
+// alive neighbours unless they are next to each other across or down, and an alive cell survives with 1 or 2.
+func TestHensel(t *testing.T) {
+	for rulestring, expected := range map[string]string{
+		"B2-a/S12":                "B2-a/S12",
+		"b3/s2-i34q":              "B3/S2-i34q",
+		"B3ceaiknjqry/S2ceaikn3":  "B3/S23",
+		"B2ce3-ck/S4tw5e":         "B2ce3-ck/S4tw5e",
+		"B2-a/S12/C4":             "B2-a/S12/C4",
+		"R1,C0,M0,S2..3,B3..3,NM": "B3/S23",
+		"R2,C0,M0,S2..4,B3..3,NN": "R2,C0,M0,S2..4,B3..3,NN",
+		"B3/S23t":                 "",
+		"B2q/S23":                 "",
+		"B9/S23":                  "",
+	} {
+		rule, err := gol.ParseRule(rulestring)
+		if expected == "" {
+			if err == nil {
+				t.Errorf("%v should not be a valid rule", rulestring)
+			}
+			continue
+		}
+		if err != nil {
+			t.Error(err)
+		} else if rule.String() != expected {
+			t.Errorf("%v was read back as %v, not %v", rulestring, rule, expected)
+		}
+	}
+
+	p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 30, Threads: 4, Rule: "B2-a/S12"}
+	alive := make(map[util.Cell]bool)
+	for _, cell := range readAliveCells("check/images/64x64x0.pgm", p.ImageWidth, p.ImageHeight) {
+		alive[cell] = true
+	}
+	for turn := 0; turn < p.Turns; turn++ {
+		next := make(map[util.Cell]bool)
+		for y := 0; y < p.ImageHeight; y++ {
+			for x := 0; x < p.ImageWidth; x++ {
+				var neighbours []util.Cell
+				for dy := -1; dy <= 1; dy++ {
+					for dx := -1; dx <= 1; dx++ {
+						if (dx != 0 || dy != 0) && alive[util.Cell{X: (x + dx + p.ImageWidth) % p.ImageWidth, Y: (y + dy + p.ImageHeight) % p.ImageHeight}] {
+							neighbours = append(neighbours, util.Cell{X: dx, Y: dy})
+						}
+					}
+				}
+				cell := util.Cell{X: x, Y: y}
+				switch {
+				case alive[cell]:
+					next[cell] = len(neighbours) == 1 || len(neighbours) == 2
+				case len(neighbours) == 2:
+					dx, dy := neighbours[0].X-neighbours[1].X, neighbours[0].Y-neighbours[1].Y
+					next[cell] = dx*dx+dy*dy != 1
+				}
+			}
+		}
+		alive = next
+	}
+	var expected []util.Cell
+	for cell, isAlive := range alive {
+		if isAlive {
+			expected = append(expected, cell)
+		}
+	}
+	events := make(chan gol.Event)
+	go gol.Run(p, events, nil)
+	for event := range events {
+		if e, ok := event.(gol.FinalTurnComplete); ok {
+			if len(e.Alive) == 0 {
+				t.Error("B2-a/S12 should not die out")
+			}
+			assertEqualBoard(t, e.Alive, expected, p)
+		}
+	}
+}
+
+// TestLattices tests hexagonal and triangular rules on the 64x64 image against neighbours worked out here from the
+// geometry: hexagons from axial coordinates, where the offset rows are skewed, and triangles from the corners they share.
+func TestLattices(t *testing.T) {
+	for rulestring, expected := range map[string]string{
+		"b2/s34h":        "B2/S34H",
+		"B4,5/S4,5,6,7L": "B45/S4567L",
+		"B4/S4,10,12L":   "B4/S4,10,12L",
+		"B2/S34/C3H":     "B2/S34/C3H",
+		"B2/S37H":        "",
+		"B4/S1,13L":      "",
+	} {
+		rule, err := gol.ParseRule(rulestring)
+		if expected == "" {
+			if err == nil {
+				t.Errorf("%v should not be a valid rule", rulestring)
+			}
+			continue
+		}
+		if err != nil {
+			t.Error(err)
+		} else if rule.String() != expected {
+			t.Errorf("%v was read back as %v, not %v", rulestring, rule, expected)
+		}
+	}
+
+	hexagonal := func(x, y, width, height int) []util.Cell {
+		q := x - (y-y&1)/2 // axial coordinates
+		var neighbours []util.Cell
+		for _, direction := range [6][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}, {1, -1}, {-1, 1}} {
+			r := y + direction[1]
+			column := q + direction[0] + (r-r&1)/2
+			neighbours = append(neighbours, util.Cell{X: (column + width) % width, Y: (r + height) % height})
+		}
+		return neighbours
+	}
+	corners := func(x, y, width, height int) map[util.Cell]bool { // in half triangles across and rows down
+		if (x+y)%2 == 0 { // pointing up
+			return map[util.Cell]bool{{X: (x + 1) % width, Y: y}: true, {X: x, Y: (y + 1) % height}: true, {X: (x + 2) % width, Y: (y + 1) % height}: true}
+		}
+		return map[util.Cell]bool{{X: x, Y: y}: true, {X: (x + 2) % width, Y: y}: true, {X: (x + 1) % width, Y: (y + 1) % height}: true}
+	}
+	triangular := func(x, y, width, height int) []util.Cell {
+		var neighbours []util.Cell
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -3; dx <= 3; dx++ {
+				other := util.Cell{X: (x + dx + width) % width, Y: (y + dy + height) % height}
+				for corner := range corners(other.X, other.Y, width, height) {
+					if (dx != 0 || dy != 0) && corners(x, y, width, height)[corner] {
+						neighbours = append(neighbours, other)
+						break
+					}
+				}
+			}
+		}
+		return neighbours
+	}
+
+	tests := []struct {
+		rule       string
+		neighbours func(x, y, width, height int) []util.Cell
+		birth      map[int]bool
+		survive    map[int]bool
+	}{
+		{"B2/S34H", hexagonal, map[int]bool{2: true}, map[int]bool{3: true, 4: true}},
+		{"B45/S4567L", triangular, map[int]bool{4: true, 5: true}, map[int]bool{4: true, 5: true, 6: true, 7: true}},
+	}
+	for _, test := range tests {
+		p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 30, Threads: 4, Rule: test.rule}
+		alive := make(map[util.Cell]bool)
+		for _, cell := range readAliveCells("check/images/64x64x0.pgm", p.ImageWidth, p.ImageHeight) {
+			alive[cell] = true
+		}
+		for turn := 0; turn < p.Turns; turn++ {
+			next := make(map[util.Cell]bool)
+			for y := 0; y < p.ImageHeight; y++ {
+				for x := 0; x < p.ImageWidth; x++ {
+					count := 0
+					for _, neighbour := range test.neighbours(x, y, p.ImageWidth, p.ImageHeight) {
+						if alive[neighbour] {
+							count++
+						}
+					}
+					cell := util.Cell{X: x, Y: y}
+					if alive[cell] && test.survive[count] || !alive[cell] && test.birth[count] {
+						next[cell] = true
+					}
+				}
+			}
+			alive = next
+		}
+		var expected []util.Cell
+		for cell := range alive {
+			expected = append(expected, cell)
+		}
+		if len(expected) == 0 {
+			t.Errorf("%v should not die out", test.rule)
+		}
+		events := make(chan gol.Event)
+		go gol.Run(p, events, nil)
+		for event := range events {
+			if e, ok := event.(gol.FinalTurnComplete); ok {
+				assertEqualBoard(t, e.Alive, expected, p)
+			}
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// emptyBoardWith returns a pattern as big as the board with another pattern at x, y in it, so stamping it clears the
+// image and leaves only the other pattern
+func emptyBoardWith(pattern gol.Pattern, p gol.Params, x int, y int) gol.Pattern {
+	board := gol.Pattern{Name: pattern.Name, Width: p.ImageWidth, Height: p.ImageHeight, States: pattern.States}
+	for _, cell := range pattern.Cells {
+		board.Cells = append(board.Cells, util.Cell{X: cell.X + x, Y: cell.Y + y})
+	}
+	return board
+}
+
+// runStates stamps a pattern on to the board before the first turn and returns the state of every cell that isn't dead
+// at the end, from the events. States are sent as the grey levels they are stored as, getting darker from 1.
+func runStates(p gol.Params, pattern gol.Pattern, states int) map[util.Cell]int {
+	commands := make(chan gol.Command, 1)
+	commands <- gol.StampPattern{Pattern: pattern}
+	events := make(chan gol.Event)
+	go gol.RunWithCommands(p, events, nil, commands)
+	board := make(map[util.Cell]int)
+	for event := range events {
+		switch e := event.(type) {
+		case gol.CellFlipped: // the image being loaded
+			board[e.Cell] = 1
+		case gol.CellStateChanged:
+			for state := 0; state < states; state++ {
+				if state == 0 && e.State == 0 || state > 0 && int(e.State) == 255*(states-state)/(states-1) {
+					board[e.Cell] = state
+				}
+			}
+			if e.State == 0 {
+				delete(board, e.Cell)
+			}
+		}
+	}
+	return board
+}
+
+// TestWireworld tests a rule table with permute symmetry and no bound variables on a 16x16 board, with a loop of
+// wire sending electrons along a wire that wraps around the edge, against Wireworld worked out here.
+func TestWireworld(t *testing.T) {
+	circuit := []string{
+		"..CCCBACC.......",
+		".C.......C......",
+		"..CCCCCCC.CCCCCC",
+		"CC..............",
+		"..C.............",
+		"..C....CCC......",
+		"...CCCCC.CCCCCCC",
+		"........C.......",
+	}
+	p := gol.Params{ImageWidth: 16, ImageHeight: 16, Turns: 60, Threads: 4, Rule: "rules/Wireworld.rule"}
+	rule, err := gol.ParseRule(p.Rule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rule.String() != p.Rule {
+		t.Errorf("%v was read back as %v", p.Rule, rule)
+	}
+	pattern := gol.Pattern{Name: "circuit", Width: p.ImageWidth, Height: p.ImageHeight}
+	expected := make(map[util.Cell]int)
+	for y, row := range circuit {
+		for x, char := range row {
+			if char != '.' {
+				pattern.Cells = append(pattern.Cells, util.Cell{X: x, Y: y})
+				pattern.States = append(pattern.States, int(char-'A')+1)
+				expected[util.Cell{X: x, Y: y}] = int(char-'A') + 1
+			}
+		}
+	}
+	for turn := 0; turn < p.Turns; turn++ {
+		next := make(map[util.Cell]int)
+		for cell, state := range expected {
+			switch state {
+			case 1, 2:
+				next[cell] = state + 1
+			case 3:
+				heads := 0
+				for dy := -1; dy <= 1; dy++ {
+					for dx := -1; dx <= 1; dx++ {
+						if expected[util.Cell{X: (cell.X + dx + 16) % 16, Y: (cell.Y + dy + 16) % 16}] == 1 {
+							heads++
+						}
+					}
+				}
+				next[cell] = 3
+				if heads == 1 || heads == 2 {
+					next[cell] = 1
+				}
+			}
+		}
+		expected = next
+	}
+	board := runStates(p, pattern, 4)
+	for y := 0; y < p.ImageHeight; y++ {
+		for x := 0; x < p.ImageWidth; x++ {
+			cell := util.Cell{X: x, Y: y}
+			if board[cell] != expected[cell] {
+				t.Errorf("cell (%d, %d) should be in state %d after %d turns, not %d", x, y, expected[cell], p.Turns, board[cell])
+			}
+		}
+	}
+}
+
+// TestLangtonsLoops tests a von Neumann rule table with rotate4 symmetry by checking that Langton's loop has built a
+// copy of itself 11 cells to the right after 151 turns, on a bounded and an unbounded board.
+func TestLangtonsLoops(t *testing.T) {
+	loop, err := gol.LoadPattern("rules/langtons-loop.rle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loop.Width != 15 || loop.Height != 10 || len(loop.States) != len(loop.Cells) {
+		t.Fatalf("the loop should be 15x10 with a state for every cell, not %dx%d with %d states", loop.Width, loop.Height, len(loop.States))
+	}
+	for _, unbounded := range []bool{false, true} {
+		p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 151, Threads: 4, Rule: "rules/Langtons-Loops.rule", Unbounded: unbounded}
+		board := runStates(p, emptyBoardWith(loop, p, 10, 30), 8)
+		for i, cell := range loop.Cells {
+			copied := util.Cell{X: cell.X + 21, Y: cell.Y + 30}
+			if board[copied] != loop.States[i] {
+				t.Errorf("unbounded %v: cell (%d, %d) of the copy should be in state %d, not %d", unbounded, cell.X, cell.Y, loop.States[i], board[copied])
+			}
+		}
+	}
+}
+
+// TestInvalidRuleTables tests that rule tables that can't be loaded are errors
+func TestInvalidRuleTables(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	header := "@RULE Invalid\n@TABLE\nn_states:3\nneighborhood:vonNeumann\n"
+	for name, text := range map[string]string{
+		"tree":        "@RULE Invalid\n@TREE\nnum_states=2\n",
+		"no-table":    "@RULE Invalid\n",
+		"states":      "@TABLE\nn_states:300\nneighborhood:Moore\n",
+		"hexagonal":   "@TABLE\nn_states:2\nneighborhood:hexagonal\n",
+		"symmetries":  header + "symmetries:rotate8\n000000\n",
+		"length":      header + "00000\n",
+		"state":       header + "000003\n",
+		"variable":    header + "0,a,0,0,0,1\n",
+		"output":      header + "var a={1,2}\n0,a,0,0,0,a\n0,0,0,0,0,b\n",
+		"unbound":     header + "var a={1,2}\nvar b={0,1}\n0,a,b,0,0,b\n0,0,0,0,0,a\n",
+		"before-vars": "@TABLE\nvar a={0,1}\nn_states:2\nneighborhood:Moore\n",
+	} {
+		path := filepath.Join(dir, name+".rule")
+		if err := ioutil.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := gol.ParseRule(path); err == nil {
+			t.Errorf("%v should not be a valid rule table", name)
+		}
+	}
+	if _, err := gol.ParseRule(filepath.Join(dir, "missing.rule")); err == nil {
+		t.Error("a missing .rule file should not be a valid rule")
+	}
+}
+
+// TestRuleTableUnbounded tests that a rule table where dead cells are born from empty space can only be used on a
+// bounded board, whilst Langton's loops, which leave empty space empty, can be used on both
+func TestRuleTableUnbounded(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "Quiescent-Birth.rule")
+	if err := ioutil.WriteFile(path, []byte("@RULE Quiescent-Birth\n@TABLE\nn_states:2\nneighborhood:vonNeumann\n0,0,0,0,0,1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, rule := range []string{path, "rules/Langtons-Loops.rule"} {
+		for _, unbounded := range []bool{false, true} {
+			p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 1, Rule: rule, Unbounded: unbounded}
+			if err := gol.CheckParams(p); (err == nil) != (rule != path || !unbounded) {
+				t.Errorf("%v with unbounded %v gave %v", rule, unbounded, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,153 @@
+//go:build !nosdl
+// +build !nosdl
+
+package sdl
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// ColourMode chooses how cells are coloured in the window
+type ColourMode int
+
+const (
+	Mono ColourMode = iota // alive cells are white, or their own colour for rules with colours, dead cells are black
+	Age                    // alive cells are coloured by how long they have been alive, and dead cells fade out
+	Heat                   // every cell is coloured by how often it has flipped
+)
+
+const (
+	oldAge      = 64 // cells alive for this many turns or more get the oldest colour
+	trailLength = 8  // number of frames a dead cell takes to fade out
+)
+
+func (mode ColourMode) String() string {
+	switch mode {
+	case Mono:
+		return "Mono"
+	case Age:
+		return "Age"
+	case Heat:
+		return "Heat"
+	default:
+		return "Incorrect ColourMode"
+	}
+}
+
+// history stores what has happened to every cell, built up from the CellFlipped and CellStateChanged events
+type history struct {
+	frame   int32        // number of frames rendered so far
+	alive   []bool       // whether each cell is currently alive
+	shade   []uint8      // the grey level of each cell, which is between black and white for dying cells
+	changed []int32      // the frame each cell was last born or died on
+	flips   []uint32     // the number of times each cell has flipped
+	hottest uint32       // the largest number of flips of any cell
+	palette []color.RGBA // the colour of each grey level, only for rules with colours, see gol.Rule.Palette
+}
+
+func newHistory(size int) history {
+	return history{
+		alive:   make([]bool, size),
+		shade:   make([]uint8, size),
+		changed: make([]int32, size),
+		flips:   make([]uint32, size),
+	}
+}
+
+// flip records a cell being born or dying on the current frame
+func (h *history) flip(i int) {
+	h.alive[i] = !h.alive[i]
+	h.shade[i] = 0
+	if h.alive[i] {
+		h.shade[i] = 0xFF
+	}
+	h.changed[i] = h.frame
+	h.flips[i]++
+	if h.flips[i] > h.hottest {
+		h.hottest = h.flips[i]
+	}
+}
+
+// setState records a cell changing to a new grey level, which only flips it if it is born or stops being alive.
+// Every grey level but black is alive for rules with colours.
+func (h *history) setState(i int, shade uint8) {
+	if (shade == 0xFF || h.palette != nil && shade != 0) != h.alive[i] {
+		h.flip(i)
+	}
+	h.shade[i] = shade
+}
+
+// colour returns the red, green and blue values of a cell in the given mode
+func (h *history) colour(i int, mode ColourMode) (uint8, uint8, uint8) {
+	age := h.frame - h.changed[i]
+	switch mode {
+	case Age:
+		if h.alive[i] { // yellow when newly born, through green to blue when old
+			return hue(60 + 180*math.Min(1, float64(age)/oldAge))
+		}
+		if age < trailLength && h.flips[i] > 0 { // a dim red trail that fades out
+			return uint8(0xC0 * (trailLength - age) / trailLength), 0, 0
+		}
+	case Heat:
+		if h.flips[i] > 0 { // black through red and yellow to white, on a log scale
+			heat := math.Log(float64(h.flips[i])+1) / math.Log(float64(h.hottest)+1)
+			return uint8(255 * math.Min(1, heat*3)), uint8(255 * math.Max(0, math.Min(1, heat*3-1))), uint8(255 * math.Max(0, heat*3-2))
+		}
+	default:
+		if h.palette != nil {
+			c := h.palette[h.shade[i]]
+			return c.R, c.G, c.B
+		}
+		return h.shade[i], h.shade[i], h.shade[i]
+	}
+	return 0, 0, 0
+}
+
+// hue returns a fully saturated colour for a hue in degrees
+func hue(degrees float64) (uint8, uint8, uint8) {
+	channel := func(offset float64) uint8 {
+		k := math.Mod(offset+degrees/60, 6)
+		return uint8(255 * (1 - math.Max(0, math.Min(1, math.Min(k, 4-k)))))
+	}
+	return channel(5), channel(3), channel(1)
+}
+
+// recolour redraws every pixel from the history of its cell
+func (w *Window) recolour() {
+	for i := range w.history.alive {
+		r, g, b := w.history.colour(i, w.colourMode)
+		alpha := uint8(0xFF)
+		if w.colourMode == Mono { // FlipPixel inverts all four bytes in mono, so dead cells need an alpha of 0
+			alpha = w.history.shade[i]
+		}
+		w.setCell(i, b, g, r, alpha)
+	}
+}
+
+// SetPalette makes the window draw the alive cells of a rule with colours in their colours, which are sent as grey
+// levels. It does nothing for rules without colours.
+func (w *Window) SetPalette(rule gol.Rule) {
+	palette := rule.Palette()
+	if palette == nil {
+		return
+	}
+	w.history.palette = make([]color.RGBA, 256)
+	for shade := range w.history.palette {
+		w.history.palette[shade] = color.RGBAModel.Convert(palette[rule.PaletteIndex(uint8(shade))]).(color.RGBA)
+	}
+}
+
+// NextColourMode cycles between the colour modes and redraws the board
+func (w *Window) NextColourMode() {
+	w.colourMode = (w.colourMode + 1) % 3
+	fmt.Println("Colour mode:", w.colourMode)
+	w.recolour()
+	err := w.texture.Update(nil, w.pixels, int(w.textureWidth()*4))
+	util.Check(err)
+	w.Present()
+}
@@ -1,18 +1,34 @@
+//go:build !nosdl
+// +build !nosdl
+
 package sdl
 
 import (
 	"fmt"
 	"github.com/veandco/go-sdl2/sdl"
 	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
 )
 
-func Run(p gol.Params, events <-chan gol.Event, keyPresses chan<- rune) {
-	w := NewWindow(int32(p.ImageWidth), int32(p.ImageHeight))
+// Run shows the board in a window. Clicking on the board stamps the selected pattern there,
+// 'n' selects the next pattern from the library and 'r' rotates it.
+// The mouse wheel, '+' and '-' zoom, the arrow keys or dragging with the right mouse button pan,
+// 'f' toggles fitting the board to the window and 'g' toggles the grid.
+// 'c' cycles between colouring cells in white, by their age, or by how often they have flipped.
+// '[' and ']' remove or add a worker thread. Hexagonal and triangular rules are drawn as hexagons or triangles, and
+// the cells of rules with colours in their colours.
+func Run(p gol.Params, events <-chan gol.Event, keyPresses chan<- rune, commands chan<- gol.Command, patterns []gol.Pattern) {
+	rule, err := gol.ParseRule(p.Rule)
+	util.Check(err)
+	w := NewLatticeWindow(int32(p.ImageWidth), int32(p.ImageHeight), rule.Lattice)
+	w.SetPalette(rule)
+	selected := 0
+	rotation := gol.Rotate0
 
 sdlLoop:
 	for {
 		event := w.PollEvent()
-		if event != nil {
+		if event != nil && !w.HandleViewEvent(event) {
 			switch e := event.(type) {
 			case *sdl.KeyboardEvent:
 				switch e.Keysym.Sym {
@@ -24,6 +40,29 @@ sdlLoop:
 					keyPresses <- 'q'
 				case sdl.K_k:
 					keyPresses <- 'k'
+				case sdl.K_LEFTBRACKET:
+					keyPresses <- '['
+				case sdl.K_RIGHTBRACKET:
+					keyPresses <- ']'
+				case sdl.K_n:
+					if len(patterns) > 0 {
+						selected = (selected + 1) % len(patterns)
+						fmt.Println("Selected pattern:", patterns[selected].Name)
+					}
+				case sdl.K_c:
+					w.NextColourMode()
+				case sdl.K_r:
+					rotation = (rotation + 1) % 4
+					fmt.Println("Pattern rotation:", int(rotation)*90)
+				}
+			case *sdl.MouseButtonEvent:
+				cell, onBoard := w.CellAt(e.X, e.Y)
+				if e.Type == sdl.MOUSEBUTTONDOWN && e.Button == sdl.BUTTON_LEFT && onBoard && len(patterns) > 0 {
+					select { // once the game has stopped taking commands, waiting would freeze the window
+					case commands <- gol.StampPattern{Pattern: patterns[selected], X: cell.X, Y: cell.Y, Rotation: rotation}:
+					default:
+						fmt.Println("Dropped pattern stamp at", cell.X, cell.Y, "as the game isn't taking commands")
+					}
 				}
 			}
 		}
@@ -36,6 +75,12 @@ sdlLoop:
 			switch e := event.(type) {
 			case gol.CellFlipped:
 				w.FlipPixel(e.Cell.X, e.Cell.Y)
+			case gol.CellsFlipped:
+				for _, cell := range e.Cells {
+					w.FlipPixel(cell.X, cell.Y)
+				}
+			case gol.CellStateChanged:
+				w.SetCellState(e.Cell.X, e.Cell.Y, e.State)
 			case gol.TurnComplete:
 				w.RenderFrame()
 			case gol.FinalTurnComplete:
@@ -0,0 +1,99 @@
+//go:build !nosdl
+// +build !nosdl
+
+package sdl
+
+import (
+	"math"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+const (
+	minShapeSize     = 4    // fewest texture pixels across a hexagon, and across the bottom of a triangle
+	maxShapeSize     = 16   // most texture pixels across, for small boards
+	shapeTextureSize = 2048 // shapes are made smaller until the texture is about this wide, down to minShapeSize
+)
+
+// shape maps the cells of a hexagonal or triangular board on to the texture pixels they are drawn with. Each texture
+// pixel belongs to at most one cell, so cells can be flipped by inverting their pixels as with square cells.
+type shape struct {
+	size          float64   // texture pixels across a hexagon, and across the bottom of a triangle
+	width, height int32     // size of the texture
+	owner         []int32   // the cell each texture pixel belongs to, or -1 for pixels off the edge of the board
+	pixels        [][]int32 // the texture pixels of each cell
+}
+
+// newShape works out which cell each texture pixel belongs to for a board of the given size. Rows are
+// size*sqrt(3)/2 pixels apart so the hexagons and triangles are close to regular.
+func newShape(lattice gol.Lattice, width, height int32) *shape {
+	size := int32(shapeTextureSize) / width &^ 1 // even, so triangles and odd rows of hexagons start on whole pixels
+	if size > maxShapeSize {
+		size = maxShapeSize
+	} else if size < minShapeSize {
+		size = minShapeSize
+	}
+	rowHeight := math.Round(float64(size) * math.Sqrt(3) / 2)
+	s := &shape{size: float64(size), pixels: make([][]int32, width*height)}
+	var cellAt func(x, y float64) (int32, int32)
+	if lattice == gol.Hexagonal {
+		s.width = width*size + size/2
+		s.height = int32(float64(height-1)*rowHeight) + size
+		cellAt = func(x, y float64) (int32, int32) { return hexagonAt(x, y, s.size, rowHeight) }
+	} else {
+		s.width = (width + 1) * size / 2
+		s.height = int32(float64(height) * rowHeight)
+		cellAt = func(x, y float64) (int32, int32) { return triangleAt(x, y, s.size, rowHeight) }
+	}
+	s.owner = make([]int32, s.width*s.height)
+	for py := int32(0); py < s.height; py++ {
+		for px := int32(0); px < s.width; px++ {
+			cellX, cellY := cellAt(float64(px)+0.5, float64(py)+0.5)
+			i := py*s.width + px
+			s.owner[i] = -1
+			if cellX >= 0 && cellY >= 0 && cellX < width && cellY < height {
+				s.owner[i] = cellY*width + cellX
+				s.pixels[s.owner[i]] = append(s.pixels[s.owner[i]], i)
+			}
+		}
+	}
+	return s
+}
+
+// hexagonAt returns the hexagon with the nearest centre to a texture position. The centres of odd rows are half a
+// hexagon further right, so the hexagons meet without gaps.
+func hexagonAt(x, y float64, size float64, rowHeight float64) (int32, int32) {
+	row := math.Floor((y - size/2) / rowHeight)
+	bestX, bestY, best := int32(-1), int32(-1), math.Inf(1)
+	for cellY := row; cellY <= row+1; cellY++ {
+		offset := 0.0
+		if int32(cellY)&1 == 1 {
+			offset = size / 2
+		}
+		cellX := math.Floor((x - offset) / size)
+		centreX, centreY := cellX*size+offset+size/2, cellY*rowHeight+size/2
+		if distance := math.Hypot(x-centreX, y-centreY); distance < best {
+			bestX, bestY, best = int32(cellX), int32(cellY), distance
+		}
+	}
+	if best > size/math.Sqrt(3) { // past the corners of the hexagons along the edge of the board
+		return -1, -1
+	}
+	return bestX, bestY
+}
+
+// triangleAt returns the triangle a texture position is in. Triangles are half a triangle apart along each row, pointing
+// up when x+y is even, so each position is covered by one of two triangles.
+func triangleAt(x, y float64, size float64, rowHeight float64) (int32, int32) {
+	cellY := math.Floor(y / rowHeight)
+	down := y/rowHeight - cellY // how far down the row the position is, from 0 to 1
+	half := size / 2
+	for cellX := math.Floor(x/half) - 1; cellX <= math.Floor(x/half); cellX++ {
+		across := math.Abs(x-(cellX+1)*half) / half // how far from the middle of the triangle, from 0 to 1
+		up := (int32(cellX)+int32(cellY))&1 == 0
+		if up && across <= down || !up && across <= 1-down {
+			return int32(cellX), int32(cellY)
+		}
+	}
+	return -1, -1
+}
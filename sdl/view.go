@@ -0,0 +1,214 @@
+//go:build !nosdl
+// +build !nosdl
+
+package sdl
+
+import (
+	"math"
+
+	"github.com/veandco/go-sdl2/sdl"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+const (
+	maxWindowSize = 1024 // largest window we open, bigger boards start zoomed out to fit
+	maxZoom       = 64   // largest number of window pixels per cell
+	minGridZoom   = 6    // the grid is only drawn when cells are at least this many pixels wide
+	panStep       = 32   // window pixels moved per arrow key press
+)
+
+// view describes where the board is drawn inside the window
+type view struct {
+	zoom       int32 // window pixels per cell, when not fitting the board to the window
+	panX, panY int32 // window position of the top left corner of the board, when not fitting
+	fit        bool  // scale the board to fit the window instead of using zoom and pan
+	grid       bool  // draw lines between cells when zoomed in far enough
+}
+
+// initialWindowSize picks a window size that shows small boards zoomed in and keeps large boards on screen
+func initialWindowSize(width, height int32) (int32, int32) {
+	largest := width
+	if height > largest {
+		largest = height
+	}
+	scale := float64(maxWindowSize/2) / float64(largest)
+	if largest > maxWindowSize/2 {
+		scale = math.Min(1, float64(maxWindowSize)/float64(largest))
+	} else {
+		scale = math.Floor(scale)
+	}
+	return int32(math.Max(1, float64(width)*scale)), int32(math.Max(1, float64(height)*scale))
+}
+
+// boardSize returns the size of the texture in cells, which is a bit more than the size of the board for hexagons
+// and triangles, along with the number of texture pixels per cell
+func (w *Window) boardSize() (float64, float64, float64) {
+	if w.shape == nil {
+		return float64(w.Width), float64(w.Height), 1
+	}
+	return float64(w.shape.width) / w.shape.size, float64(w.shape.height) / w.shape.size, w.shape.size
+}
+
+// boardRect returns where the board is drawn in window pixels, along with the size of a cell
+func (w *Window) boardRect() (sdl.Rect, float64) {
+	width, height, _ := w.boardSize()
+	if !w.view.fit {
+		zoom := w.view.zoom
+		return sdl.Rect{X: w.view.panX, Y: w.view.panY, W: int32(width * float64(zoom)), H: int32(height * float64(zoom))}, float64(zoom)
+	}
+	windowWidth, windowHeight := w.window.GetSize()
+	scale := math.Min(float64(windowWidth)/width, float64(windowHeight)/height)
+	if scale >= 1 { // keep whole pixels per cell so cells stay square and sharp
+		scale = math.Floor(scale)
+	}
+	rectWidth := int32(width * scale)
+	rectHeight := int32(height * scale)
+	return sdl.Rect{X: (windowWidth - rectWidth) / 2, Y: (windowHeight - rectHeight) / 2, W: rectWidth, H: rectHeight}, scale
+}
+
+// CellAt converts a position in the window into the cell drawn there, if there is one
+func (w *Window) CellAt(x, y int32) (util.Cell, bool) {
+	rect, scale := w.boardRect()
+	_, _, pixelsPerCell := w.boardSize()
+	pixelX := int(math.Floor(float64(x-rect.X) / scale * pixelsPerCell))
+	pixelY := int(math.Floor(float64(y-rect.Y) / scale * pixelsPerCell))
+	if w.shape != nil {
+		if pixelX < 0 || pixelY < 0 || pixelX >= int(w.shape.width) || pixelY >= int(w.shape.height) {
+			return util.Cell{}, false
+		}
+		owner := int(w.shape.owner[pixelY*int(w.shape.width)+pixelX])
+		return util.Cell{X: owner % int(w.Width), Y: owner / int(w.Width)}, owner >= 0
+	}
+	if pixelX < 0 || pixelY < 0 || pixelX >= int(w.Width) || pixelY >= int(w.Height) {
+		return util.Cell{}, false
+	}
+	return util.Cell{X: pixelX, Y: pixelY}, true
+}
+
+// leaveFit switches from fitting the board to the window to the equivalent zoom and pan
+func (w *Window) leaveFit() {
+	if !w.view.fit {
+		return
+	}
+	rect, scale := w.boardRect()
+	w.view.fit = false
+	w.view.zoom = int32(math.Max(1, math.Round(scale)))
+	w.view.panX, w.view.panY = rect.X, rect.Y
+}
+
+// Zoom changes the zoom by a number of steps, keeping the cell under the window position x, y in place
+func (w *Window) Zoom(steps int, x, y int32) {
+	w.leaveFit()
+	zoom := w.view.zoom
+	for ; steps > 0 && zoom < maxZoom; steps-- {
+		zoom *= 2
+	}
+	for ; steps < 0 && zoom > 1; steps++ {
+		zoom /= 2
+	}
+	w.view.panX = x - (x-w.view.panX)*zoom/w.view.zoom
+	w.view.panY = y - (y-w.view.panY)*zoom/w.view.zoom
+	w.view.zoom = zoom
+	w.Present()
+}
+
+// ZoomCentre zooms in or out around the centre of the window
+func (w *Window) ZoomCentre(steps int) {
+	windowWidth, windowHeight := w.window.GetSize()
+	w.Zoom(steps, windowWidth/2, windowHeight/2)
+}
+
+// Pan moves the board by dx, dy window pixels
+func (w *Window) Pan(dx, dy int32) {
+	w.leaveFit()
+	w.view.panX += dx
+	w.view.panY += dy
+	w.Present()
+}
+
+// ToggleFit switches between fitting the whole board to the window and the last zoom and pan
+func (w *Window) ToggleFit() {
+	if w.view.fit {
+		w.leaveFit()
+	} else {
+		w.view.fit = true
+	}
+	w.Present()
+}
+
+// ToggleGrid shows or hides the lines between cells
+func (w *Window) ToggleGrid() {
+	w.view.grid = !w.view.grid
+	w.Present()
+}
+
+// drawGrid draws lines between the cells that are visible in the window, when they are squares
+func (w *Window) drawGrid(rect sdl.Rect, scale float64) {
+	if !w.view.grid || scale < minGridZoom || w.shape != nil {
+		return
+	}
+	windowWidth, windowHeight := w.window.GetSize()
+	err := w.renderer.SetDrawColor(0x40, 0x40, 0x40, 0xFF)
+	util.Check(err)
+	for i := int32(0); i <= w.Width; i++ {
+		x := rect.X + int32(float64(i)*scale)
+		if x >= 0 && x < windowWidth {
+			err = w.renderer.DrawLine(x, int32(math.Max(0, float64(rect.Y))), x, int32(math.Min(float64(windowHeight), float64(rect.Y+rect.H))))
+			util.Check(err)
+		}
+	}
+	for j := int32(0); j <= w.Height; j++ {
+		y := rect.Y + int32(float64(j)*scale)
+		if y >= 0 && y < windowHeight {
+			err = w.renderer.DrawLine(int32(math.Max(0, float64(rect.X))), y, int32(math.Min(float64(windowWidth), float64(rect.X+rect.W))), y)
+			util.Check(err)
+		}
+	}
+	err = w.renderer.SetDrawColor(0, 0, 0, 0xFF)
+	util.Check(err)
+}
+
+// HandleViewEvent zooms, pans or redraws the window for mouse wheel, right button drag and resize events.
+// It returns false for events that don't affect the view.
+func (w *Window) HandleViewEvent(event sdl.Event) bool {
+	switch e := event.(type) {
+	case *sdl.MouseWheelEvent:
+		x, y, _ := sdl.GetMouseState()
+		if e.Y > 0 {
+			w.Zoom(1, x, y)
+		} else if e.Y < 0 {
+			w.Zoom(-1, x, y)
+		}
+	case *sdl.MouseMotionEvent:
+		if e.State&sdl.ButtonRMask() == 0 { // only drag the board with the right button held
+			return false
+		}
+		w.Pan(e.XRel, e.YRel)
+	case *sdl.WindowEvent:
+		w.Present()
+	case *sdl.KeyboardEvent:
+		switch e.Keysym.Sym {
+		case sdl.K_EQUALS, sdl.K_PLUS, sdl.K_KP_PLUS:
+			w.ZoomCentre(1)
+		case sdl.K_MINUS, sdl.K_KP_MINUS:
+			w.ZoomCentre(-1)
+		case sdl.K_UP:
+			w.Pan(0, panStep)
+		case sdl.K_DOWN:
+			w.Pan(0, -panStep)
+		case sdl.K_LEFT:
+			w.Pan(panStep, 0)
+		case sdl.K_RIGHT:
+			w.Pan(-panStep, 0)
+		case sdl.K_f:
+			w.ToggleFit()
+		case sdl.K_g:
+			w.ToggleGrid()
+		default:
+			return false
+		}
+	default:
+		return false
+	}
+	return true
+}
@@ -1,45 +1,72 @@
+//go:build !nosdl
+// +build !nosdl
+
 package sdl
 
 import (
 	"fmt"
 
 	"github.com/veandco/go-sdl2/sdl"
+	"uk.ac.bris.cs/gameoflife/gol"
 	"uk.ac.bris.cs/gameoflife/util"
 )
 
 type Window struct {
-	Width, Height int32
+	Width, Height int32 // size of the board, one texture pixel per cell unless the cells are hexagons or triangles
 	window        *sdl.Window
 	renderer      *sdl.Renderer
 	texture       *sdl.Texture
 	pixels        []byte
+	shape         *shape // where hexagonal or triangular cells are drawn in the texture, nil for square cells
+	view          view
+	history       history
+	colourMode    ColourMode
 }
 
 func filterEvent(e sdl.Event, userdata interface{}) bool {
-	return e.GetType() == sdl.KEYDOWN || e.GetType() == sdl.QUIT
+	switch e.GetType() {
+	case sdl.KEYDOWN, sdl.MOUSEBUTTONDOWN, sdl.MOUSEMOTION, sdl.MOUSEWHEEL, sdl.WINDOWEVENT, sdl.QUIT:
+		return true
+	}
+	return false
 }
 
+// NewWindow opens a resizable window for a board of the given size, starting with the board fitted to the window
 func NewWindow(width, height int32) *Window {
+	return NewLatticeWindow(width, height, gol.Square)
+}
+
+// NewLatticeWindow opens a window for a board of cells with the shape of the lattice, drawing hexagons or triangles
+// several texture pixels across instead of one pixel per cell
+func NewLatticeWindow(width, height int32, lattice gol.Lattice) *Window {
 	err := sdl.Init(sdl.INIT_EVERYTHING)
 	util.Check(err)
-	window, err := sdl.CreateWindow("GOL GUI", sdl.WINDOWPOS_CENTERED, sdl.WINDOWPOS_CENTERED, width, height, sdl.WINDOW_SHOWN)
+	var cellShape *shape
+	textureWidth, textureHeight := width, height
+	if lattice != gol.Square {
+		cellShape = newShape(lattice, width, height)
+		textureWidth, textureHeight = cellShape.width, cellShape.height
+	}
+	windowWidth, windowHeight := initialWindowSize(width, height)
+	window, err := sdl.CreateWindow("GOL GUI", sdl.WINDOWPOS_CENTERED, sdl.WINDOWPOS_CENTERED, windowWidth, windowHeight, sdl.WINDOW_SHOWN|sdl.WINDOW_RESIZABLE)
 	util.Check(err)
 	renderer, err := sdl.CreateRenderer(window, -1, sdl.WINDOW_SHOWN)
 	util.Check(err)
-	sdl.SetHint(sdl.HINT_RENDER_SCALE_QUALITY, "linear")
-	err = renderer.SetLogicalSize(width, height)
-	util.Check(err)
-	texture, err := renderer.CreateTexture(sdl.PIXELFORMAT_ARGB8888, sdl.TEXTUREACCESS_STATIC, width, height)
+	sdl.SetHint(sdl.HINT_RENDER_SCALE_QUALITY, "nearest") // keep cells as sharp squares when zoomed in
+	texture, err := renderer.CreateTexture(sdl.PIXELFORMAT_ARGB8888, sdl.TEXTUREACCESS_STATIC, textureWidth, textureHeight)
 	util.Check(err)
 
 	sdl.SetEventFilterFunc(filterEvent, nil)
 	return &Window{
-		width,
-		height,
-		window,
-		renderer,
-		texture,
-		make([]byte, width*height*4),
+		Width:    width,
+		Height:   height,
+		window:   window,
+		renderer: renderer,
+		texture:  texture,
+		pixels:   make([]byte, textureWidth*textureHeight*4),
+		shape:    cellShape,
+		view:     view{zoom: 1, fit: true},
+		history:  newHistory(int(width * height)),
 	}
 }
 
@@ -53,13 +80,69 @@ func (w *Window) Destroy() {
 	sdl.Quit()
 }
 
+// RenderFrame copies the pixels into the texture and draws them, this should only happen once a turn is complete
 func (w *Window) RenderFrame() {
-	err := w.texture.Update(nil, w.pixels, int(w.Width*4))
+	if w.colourMode != Mono { // colours depend on the age of cells, so they all need updating each frame
+		w.recolour()
+	}
+	w.history.frame++
+	err := w.texture.Update(nil, w.pixels, int(w.textureWidth()*4))
 	util.Check(err)
-	err = w.renderer.Clear()
+	w.Present()
+}
+
+// textureWidth returns the width of the texture in pixels
+func (w *Window) textureWidth() int32 {
+	if w.shape != nil {
+		return w.shape.width
+	}
+	return w.Width
+}
+
+// setCell sets the bytes of every texture pixel a cell is drawn with
+func (w *Window) setCell(i int, b, g, r, a byte) {
+	if w.shape == nil {
+		w.setTexel(i, b, g, r, a)
+		return
+	}
+	for _, texel := range w.shape.pixels[i] {
+		w.setTexel(int(texel), b, g, r, a)
+	}
+}
+
+// invertCell inverts the bytes of every texture pixel a cell is drawn with
+func (w *Window) invertCell(i int) {
+	if w.shape == nil {
+		w.invertTexel(i)
+		return
+	}
+	for _, texel := range w.shape.pixels[i] {
+		w.invertTexel(int(texel))
+	}
+}
+
+func (w *Window) setTexel(i int, b, g, r, a byte) {
+	w.pixels[4*i+0] = b
+	w.pixels[4*i+1] = g
+	w.pixels[4*i+2] = r
+	w.pixels[4*i+3] = a
+}
+
+func (w *Window) invertTexel(i int) {
+	w.pixels[4*i+0] = ^w.pixels[4*i+0]
+	w.pixels[4*i+1] = ^w.pixels[4*i+1]
+	w.pixels[4*i+2] = ^w.pixels[4*i+2]
+	w.pixels[4*i+3] = ^w.pixels[4*i+3]
+}
+
+// Present redraws the last rendered frame, e.g. after the view has been zoomed or panned
+func (w *Window) Present() {
+	err := w.renderer.Clear()
 	util.Check(err)
-	err = w.renderer.Copy(w.texture, nil, nil)
+	rect, scale := w.boardRect()
+	err = w.renderer.Copy(w.texture, nil, &rect)
 	util.Check(err)
+	w.drawGrid(rect, scale)
 	w.renderer.Present()
 }
 
@@ -69,10 +152,10 @@ func (w *Window) PollEvent() sdl.Event {
 
 func (w *Window) SetPixel(x, y int) {
 	width := int(w.Width)
-	w.pixels[4*(y*width+x)+0] = 0xFF
-	w.pixels[4*(y*width+x)+1] = 0xFF
-	w.pixels[4*(y*width+x)+2] = 0xFF
-	w.pixels[4*(y*width+x)+3] = 0xFF
+	if !w.history.alive[y*width+x] {
+		w.history.flip(y*width + x)
+	}
+	w.setCell(y*width+x, 0xFF, 0xFF, 0xFF, 0xFF)
 }
 
 func (w *Window) FlipPixel(x, y int) {
@@ -81,16 +164,34 @@ func (w *Window) FlipPixel(x, y int) {
 	}
 
 	width := int(w.Width)
-	w.pixels[4*(y*width+x)+0] = ^w.pixels[4*(y*width+x)+0]
-	w.pixels[4*(y*width+x)+1] = ^w.pixels[4*(y*width+x)+1]
-	w.pixels[4*(y*width+x)+2] = ^w.pixels[4*(y*width+x)+2]
-	w.pixels[4*(y*width+x)+3] = ^w.pixels[4*(y*width+x)+3]
+	w.history.flip(y*width + x)
+	if w.colourMode != Mono { // the pixel will be recoloured when the frame is rendered
+		return
+	}
+	w.invertCell(y*width + x)
+}
+
+// SetCellState draws a cell with the grey level of its new state, for rules where cells can be dying, or in the colour
+// of that grey level for rules with colours
+func (w *Window) SetCellState(x, y int, shade uint8) {
+	if x < 0 || y < 0 || x >= int(w.Width) || y >= int(w.Height) {
+		panic(fmt.Sprintf("CellStateChanged event at (%d, %d) is outside the bounds of the window.", x, y))
+	}
+
+	width := int(w.Width)
+	w.history.setState(y*width+x, shade)
+	if w.colourMode != Mono { // the pixel will be recoloured when the frame is rendered
+		return
+	}
+	r, g, b := w.history.colour(y*width+x, Mono)
+	w.setCell(y*width+x, b, g, r, shade)
 }
 
+// CountPixels returns the number of alive cells, which no longer have to be white pixels in the colour modes
 func (w *Window) CountPixels() int {
 	count := 0
-	for i := 0; i < int(w.Width)*int(w.Height)*4; i += 4 {
-		if w.pixels[i] == 0xFF {
+	for _, alive := range w.history.alive {
+		if alive {
 			count++
 		}
 	}
@@ -101,4 +202,7 @@ func (w *Window) ClearPixels() {
 	for i := range w.pixels {
 		w.pixels[i] = 0
 	}
+	palette := w.history.palette
+	w.history = newHistory(int(w.Width * w.Height))
+	w.history.palette = palette
 }
@@ -1,3 +1,6 @@
+//go:build !nosdl
+// +build !nosdl
+
 package main
 
 import (
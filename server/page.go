@@ -0,0 +1,67 @@
+package server
+
+// page is the browser viewer, which draws the board on a canvas from the /events stream
+const page = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Game of Life</title>
+<style>
+  body { background: #111; color: #ddd; font-family: monospace; margin: 1em; }
+  canvas { image-rendering: pixelated; border: 1px solid #444; max-width: 95vw; max-height: 85vh; }
+  button { font-family: monospace; margin-right: 0.5em; }
+</style>
+</head>
+<body>
+<div>
+  <button onclick="key('p')">Pause / resume</button>
+  <button onclick="key('s')">Save image</button>
+  <button onclick="key('q')">Quit</button>
+  <span id="status">Connecting...</span>
+</div>
+<p><canvas id="board"></canvas></p>
+<script>
+const canvas = document.getElementById("board");
+const context = canvas.getContext("2d");
+const status = document.getElementById("status");
+let image, state = "Executing";
+
+function key(k) {
+  fetch("/keys/" + k, {method: "POST"});
+}
+
+function flip(cells) {
+  for (let i = 0; i < cells.length; i += 2) {
+    const p = 4 * (cells[i + 1] * image.width + cells[i]);
+    const v = image.data[p] ? 0 : 255;
+    image.data[p] = image.data[p + 1] = image.data[p + 2] = v;
+  }
+}
+
+function show(turn, alive) {
+  context.putImageData(image, 0, 0);
+  status.textContent = "Turn " + turn + " | Alive " + alive + " | " + state;
+}
+
+const events = new EventSource("/events");
+events.addEventListener("snapshot", e => {
+  const s = JSON.parse(e.data);
+  canvas.width = s.width;
+  canvas.height = s.height;
+  canvas.style.width = Math.max(s.width, 512) + "px";
+  image = context.createImageData(s.width, s.height);
+  for (let i = 3; i < image.data.length; i += 4) image.data[i] = 255;
+  flip(s.cells || []);
+  state = s.state;
+  show(s.turn, s.alive);
+});
+events.addEventListener("turn", e => {
+  const d = JSON.parse(e.data);
+  flip(d.flipped || []);
+  show(d.turn, d.alive);
+});
+events.onerror = () => { status.textContent += " | Disconnected"; events.close(); };
+</script>
+</body>
+</html>
+`
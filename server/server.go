@@ -0,0 +1,303 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+const clientBuffer = 64 // turns a browser can fall behind by before it is sent a whole new snapshot
+
+// state is what the browser is told about the game
+type state struct {
+	Turn    int    `json:"turn"`
+	State   string `json:"state"`
+	Alive   int    `json:"alive"`
+	Width   int    `json:"width"`
+	Height  int    `json:"height"`
+	Message string `json:"message,omitempty"`
+	Cells   []int  `json:"cells,omitempty"` // alive cells as x0, y0, x1, y1, ...
+}
+
+// delta is sent to browsers after every turn with the cells that flipped during it
+type delta struct {
+	Turn    int   `json:"turn"`
+	Alive   int   `json:"alive"`
+	Flipped []int `json:"flipped"` // flipped cells as x0, y0, x1, y1, ...
+}
+
+// client is one browser watching the event stream
+type client struct {
+	deltas chan delta
+	resync chan struct{} // signalled when deltas were dropped, so the client needs a snapshot
+}
+
+// server stores the board, as built from CellFlipped events, and the browsers watching it
+type server struct {
+	lock    sync.Mutex
+	p       gol.Params
+	cells   map[util.Cell]bool
+	turn    int
+	state   gol.State
+	message string
+	flipped []int // cells flipped since the last TurnComplete
+	clients map[*client]bool
+	keys    chan<- rune
+	colours bool // alive cells have colours, so every state that isn't dead is alive
+}
+
+// Run serves the game over HTTP on addr until the game is over:
+//
+//	GET  /        a page that draws the board on a canvas
+//	GET  /state   the current state as JSON, including every alive cell
+//	GET  /events  a server-sent event stream of a snapshot, then the cells flipped each turn
+//	POST /keys/s, /keys/p and /keys/q send the same key presses as the SDL window
+func Run(addr string, p gol.Params, events <-chan gol.Event, keyPresses chan<- rune) {
+	s := newServer(p, keyPresses)
+	httpServer := &http.Server{Addr: addr, Handler: s.handler()}
+	go func() {
+		err := httpServer.ListenAndServe()
+		if err != http.ErrServerClosed {
+			util.Check(err)
+		}
+	}()
+	fmt.Println("Serving on", addr)
+
+	for event := range events {
+		s.handleEvent(event)
+		if _, ok := event.(gol.FinalTurnComplete); ok {
+			break
+		}
+	}
+	_ = httpServer.Close()
+}
+
+// newServer creates a server for a game that hasn't started, which sends key presses to keyPresses
+func newServer(p gol.Params, keyPresses chan<- rune) *server {
+	rule, err := gol.ParseRule(p.Rule)
+	util.Check(err)
+	return &server{
+		p:       p,
+		cells:   make(map[util.Cell]bool),
+		state:   gol.Executing,
+		clients: make(map[*client]bool),
+		keys:    keyPresses,
+		colours: rule.Colours > 0,
+	}
+}
+
+// handler routes requests to the endpoints listed on Run
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handlePage)
+	mux.HandleFunc("/state", s.handleState)
+	mux.HandleFunc("/events", s.handleEvents)
+	mux.HandleFunc("/keys/", s.handleKey)
+	return mux
+}
+
+// handleEvent updates the board and sends deltas to the browsers once a turn is complete
+func (s *server) handleEvent(event gol.Event) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	switch e := event.(type) {
+	case gol.CellFlipped:
+		s.flip(e.Cell)
+	case gol.CellsFlipped:
+		for _, cell := range e.Cells {
+			s.flip(cell)
+		}
+	case gol.CellStateChanged:
+		if alive := e.State == 255 || s.colours && e.State != 0; alive != s.cells[e.Cell] { // dying cells are shown as dead
+			s.flip(e.Cell)
+		}
+	case gol.TurnComplete:
+		s.turn = e.CompletedTurns
+		s.broadcast(delta{Turn: s.turn, Alive: len(s.cells), Flipped: s.flipped})
+		s.flipped = nil
+	case gol.FinalTurnComplete:
+		s.turn = e.CompletedTurns
+		s.state = gol.Quitting
+		s.broadcast(delta{Turn: s.turn, Alive: len(s.cells), Flipped: s.flipped})
+		s.flipped = nil
+	case gol.StateChange:
+		s.state = e.NewState
+	default:
+		if len(event.String()) > 0 {
+			s.message = event.String()
+			fmt.Printf("Completed Turns %-8v%v\n", event.GetCompletedTurns(), event)
+		}
+	}
+}
+
+// flip records a cell changing state, ready to be sent with the next delta
+func (s *server) flip(cell util.Cell) {
+	s.cells[cell] = !s.cells[cell]
+	if !s.cells[cell] {
+		delete(s.cells, cell)
+	}
+	s.flipped = append(s.flipped, cell.X, cell.Y)
+}
+
+// broadcast sends a delta to every browser, without waiting for slow ones
+func (s *server) broadcast(d delta) {
+	for c := range s.clients {
+		select {
+		case c.deltas <- d:
+		default: // the browser has fallen behind, so it will need a whole new snapshot
+			select {
+			case c.resync <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// snapshot returns the state as of the last completed turn, including every alive cell if withCells is set. Flips
+// of the turn being worked out are left out, as browsers are sent them with the next delta, which toggles them.
+func (s *server) snapshot(withCells bool) state {
+	cells := s.completedCells()
+	snapshot := state{
+		Turn:    s.turn,
+		State:   s.state.String(),
+		Alive:   len(cells),
+		Width:   s.p.ImageWidth,
+		Height:  s.p.ImageHeight,
+		Message: s.message,
+	}
+	if withCells {
+		snapshot.Cells = make([]int, 0, 2*len(cells))
+		for _, cell := range sortedCells(cells) {
+			snapshot.Cells = append(snapshot.Cells, cell.X, cell.Y)
+		}
+	}
+	return snapshot
+}
+
+// completedCells returns the alive cells as of the last completed turn, by undoing the flips not yet sent
+func (s *server) completedCells() map[util.Cell]bool {
+	if len(s.flipped) == 0 {
+		return s.cells
+	}
+	cells := make(map[util.Cell]bool, len(s.cells))
+	for cell := range s.cells {
+		cells[cell] = true
+	}
+	for i := 0; i < len(s.flipped); i += 2 {
+		cell := util.Cell{X: s.flipped[i], Y: s.flipped[i+1]}
+		cells[cell] = !cells[cell]
+		if !cells[cell] {
+			delete(cells, cell)
+		}
+	}
+	return cells
+}
+
+func (s *server) handlePage(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write([]byte(page))
+}
+
+func (s *server) handleState(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	snapshot := s.snapshot(r.URL.Query().Get("cells") != "false")
+	s.lock.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(snapshot)
+}
+
+// handleEvents streams a snapshot followed by a delta for every turn, as server-sent events
+func (s *server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	c := &client{deltas: make(chan delta, clientBuffer), resync: make(chan struct{}, 1)}
+	s.lock.Lock()
+	snapshot := s.snapshot(true)
+	s.clients[c] = true // registered under the same lock as the snapshot, so no turn is missed between them
+	s.lock.Unlock()
+	defer func() {
+		s.lock.Lock()
+		delete(s.clients, c)
+		s.lock.Unlock()
+	}()
+
+	send := func(name string, data interface{}) bool {
+		encoded, err := json.Marshal(data)
+		util.Check(err)
+		_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, encoded)
+		flusher.Flush()
+		return err == nil
+	}
+	if !send("snapshot", snapshot) {
+		return
+	}
+	for {
+		select {
+		case d := <-c.deltas:
+			if !send("turn", d) {
+				return
+			}
+		case <-c.resync:
+			s.lock.Lock()
+			for len(c.deltas) > 0 { // everything queued is older than the new snapshot
+				<-c.deltas
+			}
+			snapshot := s.snapshot(true)
+			s.lock.Unlock()
+			if !send("snapshot", snapshot) {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// handleKey sends the key at the end of the path, e.g. POST /keys/p pauses the game. Keys aren't waited on, so once
+// the game has finished or stopped reading them the request fails instead of hanging.
+func (s *server) handleKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "keys must be sent with POST", http.StatusMethodNotAllowed)
+		return
+	}
+	key := r.URL.Path[len("/keys/"):]
+	switch key {
+	case "s", "p", "q":
+		select {
+		case s.keys <- rune(key[0]):
+			w.WriteHeader(http.StatusNoContent)
+		case <-r.Context().Done():
+		default:
+			http.Error(w, "the game isn't taking key presses", http.StatusServiceUnavailable)
+		}
+	default:
+		http.Error(w, "unknown key "+key, http.StatusNotFound)
+	}
+}
+
+// sortedCells returns alive cells in reading order, which keeps snapshots stable for tools diffing them
+func sortedCells(cells map[util.Cell]bool) []util.Cell {
+	sorted := make([]util.Cell, 0, len(cells))
+	for cell := range cells {
+		sorted = append(sorted, cell)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Y < sorted[j].Y || sorted[i].Y == sorted[j].Y && sorted[i].X < sorted[j].X
+	})
+	return sorted
+}
@@ -0,0 +1,275 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// sseEvent is one server-sent event, with its data left as JSON
+type sseEvent struct {
+	name string
+	data string
+}
+
+// readEvent reads the next server-sent event from a stream
+func readEvent(reader *bufio.Reader) (sseEvent, error) {
+	var event sseEvent
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return event, err
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			return event, nil
+		case strings.HasPrefix(line, "event: "):
+			event.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			event.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+// turn sends the events of a turn that flips some cells
+func turn(s *server, completedTurns int, cells ...util.Cell) {
+	for _, cell := range cells {
+		s.handleEvent(gol.CellFlipped{CompletedTurns: completedTurns, Cell: cell})
+	}
+	s.handleEvent(gol.TurnComplete{CompletedTurns: completedTurns + 1})
+}
+
+// streamWriter is a ResponseWriter for the event stream that hands every write to the test, waiting until it is taken,
+// so the test decides how far behind the browser falls
+type streamWriter struct {
+	header http.Header
+	writes chan string
+	closed chan struct{}
+}
+
+func (w *streamWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *streamWriter) Write(data []byte) (int, error) {
+	select {
+	case w.writes <- string(data):
+		return len(data), nil
+	case <-w.closed:
+		return 0, errors.New("the browser has gone")
+	}
+}
+
+func (w *streamWriter) WriteHeader(int) {}
+
+func (w *streamWriter) Flush() {}
+
+// TestState tests that /state has the board built up from the events, with or without its cells
+func TestState(t *testing.T) {
+	s := newServer(gol.Params{ImageWidth: 16, ImageHeight: 16}, nil)
+	web := httptest.NewServer(s.handler())
+	defer web.Close()
+	turn(s, 0, util.Cell{X: 3, Y: 2}, util.Cell{X: 1, Y: 2}, util.Cell{X: 5, Y: 0})
+	turn(s, 1, util.Cell{X: 1, Y: 2})
+
+	for _, test := range []struct {
+		query    string
+		expected state
+	}{
+		{"", state{Turn: 2, State: "Executing", Alive: 2, Width: 16, Height: 16, Cells: []int{5, 0, 3, 2}}},
+		{"?cells=false", state{Turn: 2, State: "Executing", Alive: 2, Width: 16, Height: 16}},
+	} {
+		response, err := http.Get(web.URL + "/state" + test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var given state
+		err = json.NewDecoder(response.Body).Decode(&given)
+		response.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(given, test.expected) {
+			t.Errorf("/state%v gave %+v, not %+v", test.query, given, test.expected)
+		}
+	}
+}
+
+// TestStates tests that cells of every colour are counted as alive for rules with colours, but dying cells are counted
+// as dead for rules with dying states
+func TestStates(t *testing.T) {
+	for rule, expected := range map[string][]int{"QuadLife": {0, 1, 1, 1, 2, 1, 3, 1}, "B2/S/C4": {0, 1}} {
+		s := newServer(gol.Params{ImageWidth: 16, ImageHeight: 16, Rule: rule}, nil)
+		for i, state := range []uint8{255, 191, 127, 63} {
+			s.handleEvent(gol.CellStateChanged{CompletedTurns: 0, Cell: util.Cell{X: i, Y: 1}, State: state})
+		}
+		s.handleEvent(gol.TurnComplete{CompletedTurns: 1})
+		if snapshot := s.snapshot(true); !reflect.DeepEqual(snapshot.Cells, expected) || snapshot.Alive != len(expected)/2 {
+			t.Errorf("%v: the alive cells should be %v, not %v", rule, expected, snapshot.Cells)
+		}
+	}
+}
+
+// TestEvents tests that /events starts with a snapshot of the board and then sends the cells flipped each turn
+func TestEvents(t *testing.T) {
+	s := newServer(gol.Params{ImageWidth: 16, ImageHeight: 16}, nil)
+	web := httptest.NewServer(s.handler())
+	defer web.Close()
+	turn(s, 0, util.Cell{X: 4, Y: 4})
+
+	response, err := http.Get(web.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	reader := bufio.NewReader(response.Body)
+	event, err := readEvent(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var snapshot state
+	if err := json.Unmarshal([]byte(event.data), &snapshot); err != nil || event.name != "snapshot" {
+		t.Fatalf("the stream should start with a snapshot, not %v %v", event.name, event.data)
+	}
+	if snapshot.Turn != 1 || !reflect.DeepEqual(snapshot.Cells, []int{4, 4}) {
+		t.Errorf("the snapshot should be of turn 1 with cell (4, 4) alive, not %+v", snapshot)
+	}
+
+	turn(s, 1, util.Cell{X: 4, Y: 4}, util.Cell{X: 7, Y: 1})
+	turn(s, 2)
+	for _, expected := range []delta{{Turn: 2, Alive: 1, Flipped: []int{4, 4, 7, 1}}, {Turn: 3, Alive: 1, Flipped: nil}} {
+		event, err := readEvent(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var given delta
+		if err := json.Unmarshal([]byte(event.data), &given); err != nil || event.name != "turn" {
+			t.Fatalf("expected a turn, not %v %v", event.name, event.data)
+		}
+		if !reflect.DeepEqual(given, expected) {
+			t.Errorf("expected %+v, not %+v", expected, given)
+		}
+	}
+}
+
+// TestMidTurn tests that a browser connecting part way through the flips of a turn, here the image being loaded, ends
+// up with the same board as the server once it has toggled the cells of the next delta
+func TestMidTurn(t *testing.T) {
+	s := newServer(gol.Params{ImageWidth: 16, ImageHeight: 16}, nil)
+	web := httptest.NewServer(s.handler())
+	defer web.Close()
+	s.handleEvent(gol.CellFlipped{CompletedTurns: 0, Cell: util.Cell{X: 2, Y: 3}})
+
+	response, err := http.Get(web.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	reader := bufio.NewReader(response.Body)
+	event, err := readEvent(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var snapshot state
+	if err := json.Unmarshal([]byte(event.data), &snapshot); err != nil || event.name != "snapshot" {
+		t.Fatalf("the stream should start with a snapshot, not %v %v", event.name, event.data)
+	}
+	s.handleEvent(gol.CellFlipped{CompletedTurns: 0, Cell: util.Cell{X: 5, Y: 6}})
+	s.handleEvent(gol.TurnComplete{CompletedTurns: 1})
+	event, err = readEvent(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d delta
+	if err := json.Unmarshal([]byte(event.data), &d); err != nil || event.name != "turn" {
+		t.Fatalf("expected a turn, not %v %v", event.name, event.data)
+	}
+
+	browser := make(map[util.Cell]bool) // toggled like the page does
+	for _, cells := range [][]int{snapshot.Cells, d.Flipped} {
+		for i := 0; i < len(cells); i += 2 {
+			cell := util.Cell{X: cells[i], Y: cells[i+1]}
+			browser[cell] = !browser[cell]
+			if !browser[cell] {
+				delete(browser, cell)
+			}
+		}
+	}
+	if !reflect.DeepEqual(browser, s.cells) || snapshot.Alive != 0 || d.Alive != 2 {
+		t.Errorf("the browser has %v from a snapshot of %d cells and a delta of %d, not %v", browser, snapshot.Alive, d.Alive, s.cells)
+	}
+}
+
+// TestResync tests that a browser that falls more than clientBuffer turns behind is sent a new snapshot of the latest
+// board instead of the turns it missed
+func TestResync(t *testing.T) {
+	s := newServer(gol.Params{ImageWidth: 16, ImageHeight: 16}, nil)
+	w := &streamWriter{header: make(http.Header), writes: make(chan string), closed: make(chan struct{})}
+	defer close(w.closed)
+	request := httptest.NewRequest(http.MethodGet, "/events", nil)
+	go s.handleEvents(w, request)
+	if write := <-w.writes; !strings.HasPrefix(write, "event: snapshot") {
+		t.Fatalf("the stream should start with a snapshot, not %v", write)
+	}
+
+	turns := clientBuffer + 10 // the stream is stuck writing the first of them, so the rest overflow
+	for i := 0; i < turns; i++ {
+		turn(s, i, util.Cell{X: i % 16, Y: i / 16})
+	}
+	s.handleEvent(gol.CellFlipped{CompletedTurns: turns, Cell: util.Cell{X: 15, Y: 15}}) // part of a turn, sent later
+	for write := range w.writes {
+		if !strings.HasPrefix(write, "event: snapshot") {
+			continue
+		}
+		var snapshot state
+		util.Check(json.Unmarshal([]byte(strings.TrimSuffix(strings.SplitN(write, "data: ", 2)[1], "\n\n")), &snapshot))
+		if snapshot.Turn != turns || len(snapshot.Cells) != 2*turns {
+			t.Errorf("the new snapshot should be of turn %d with %d alive cells, not %+v", turns, turns, snapshot)
+		}
+		return
+	}
+}
+
+// TestKeys tests that keys are sent to the game, and that requests fail instead of hanging once it stops reading them
+func TestKeys(t *testing.T) {
+	keyPresses := make(chan rune, 1)
+	s := newServer(gol.Params{ImageWidth: 16, ImageHeight: 16}, keyPresses)
+	web := httptest.NewServer(s.handler())
+	defer web.Close()
+
+	for _, test := range []struct {
+		method string
+		key    string
+		status int
+	}{
+		{http.MethodPost, "p", http.StatusNoContent},
+		{http.MethodPost, "q", http.StatusServiceUnavailable}, // nothing has read the p yet
+		{http.MethodPost, "x", http.StatusNotFound},
+		{http.MethodGet, "p", http.StatusMethodNotAllowed},
+	} {
+		request, err := http.NewRequest(test.method, web.URL+"/keys/"+test.key, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		response.Body.Close()
+		if response.StatusCode != test.status {
+			t.Errorf("%v /keys/%v gave %d, not %d", test.method, test.key, response.StatusCode, test.status)
+		}
+	}
+	if key := <-keyPresses; key != 'p' {
+		t.Errorf("the game should have been sent p, not %c", key)
+	}
+}
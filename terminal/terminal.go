@@ -0,0 +1,317 @@
+package terminal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+const frameInterval = 50 * time.Millisecond // draw at most 20 frames a second, terminals are slow
+
+// ANSI escape codes used to draw
+const (
+	enterScreen = "\x1b[?1049h\x1b[?25l" // switch to the alternate screen and hide the cursor
+	leaveScreen = "\x1b[?25h\x1b[?1049l" // show the cursor and switch back to the normal screen
+	home        = "\x1b[H"               // move the cursor to the top left
+	clearLine   = "\x1b[K"               // clear the rest of the line
+	reverse     = "\x1b[7m"              // swap the foreground and background colours
+	reset       = "\x1b[0m"
+)
+
+// keys that move the viewport, read from the arrow key escape sequences
+const (
+	moveUp rune = iota
+	moveDown
+	moveLeft
+	moveRight
+	toggleBraille
+)
+
+// renderer stores the board, as built from CellFlipped events, and the part of it shown in the terminal
+type renderer struct {
+	width, height int
+	cells         []bool
+	alive         int
+	turn          int
+	state         gol.State
+	message       string // the last event that had something to say
+	braille       bool   // draw 2x4 cells per character instead of 1x2
+	viewX, viewY  int    // the top left cell of the viewport
+	out           *bufio.Writer
+	size          func() (int, int) // the columns and rows of the terminal
+	colours       bool              // alive cells have colours, so every state that isn't dead is alive
+}
+
+// newRenderer creates a renderer for an empty board that draws to out, in a terminal of the size given by size
+func newRenderer(p gol.Params, out io.Writer, size func() (int, int)) *renderer {
+	rule, err := gol.ParseRule(p.Rule)
+	util.Check(err)
+	return &renderer{
+		width:   p.ImageWidth,
+		height:  p.ImageHeight,
+		cells:   make([]bool, p.ImageWidth*p.ImageHeight),
+		state:   gol.Executing,
+		out:     bufio.NewWriter(out),
+		size:    size,
+		colours: rule.Colours > 0,
+	}
+}
+
+// Run draws the board in the terminal until the game is over. Arrow keys scroll the viewport, 'b' switches
+// between half-block and braille characters, and 's', 'p', 'q', '[' and ']' are sent on to the game.
+func Run(p gol.Params, events <-chan gol.Event, keyPresses chan<- rune) {
+	restore := makeRaw(os.Stdin)
+	defer restore()
+
+	r := newRenderer(p, os.Stdout, func() (int, int) { return size(os.Stdout) })
+	_, _ = r.out.WriteString(enterScreen)
+	defer func() {
+		_, _ = r.out.WriteString(leaveScreen)
+		util.Check(r.out.Flush())
+		fmt.Printf("Completed Turns %-8v%v\n", r.turn, r.message)
+	}()
+
+	moves := make(chan rune, 10)
+	keys, closeKeys := openKeys(os.Stdin)
+	done := make(chan struct{}) // closed once the game is over, so the key reader stops instead of outliving Run
+	defer func() {
+		close(done)
+		closeKeys()
+	}()
+	go readKeys(keys, keyPresses, moves, done)
+
+	ticker := time.NewTicker(frameInterval)
+	defer ticker.Stop()
+	var lastDraw time.Time
+	midTurn := false // only draw whole turns, never part way through the CellFlipped events of one
+	stale := false   // a turn completed without being drawn
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			switch e := event.(type) {
+			case gol.CellFlipped:
+				r.flip(e.Cell)
+				midTurn = true
+			case gol.CellsFlipped:
+				for _, cell := range e.Cells {
+					r.flip(cell)
+				}
+				midTurn = true
+			case gol.CellStateChanged:
+				r.setState(e.Cell, e.State)
+				midTurn = true
+			case gol.TurnComplete:
+				r.turn = e.CompletedTurns
+				midTurn = false
+				stale = true
+				if time.Since(lastDraw) >= frameInterval {
+					r.draw()
+					lastDraw, stale = time.Now(), false
+				}
+			case gol.FinalTurnComplete:
+				r.turn = e.CompletedTurns
+				r.draw()
+				return
+			case gol.StateChange:
+				r.state = e.NewState
+				r.message = e.String()
+				stale = true
+			default:
+				if len(event.String()) > 0 {
+					r.message = event.String()
+					stale = true
+				}
+			}
+		case move := <-moves:
+			r.move(move)
+			stale = true
+		case <-ticker.C:
+			if stale && !midTurn {
+				r.draw()
+				lastDraw, stale = time.Now(), false
+			}
+		}
+	}
+}
+
+// readKeys reads single key presses, sending game keys to the game and movement keys to the renderer, until the input
+// ends or done is closed. Nothing is sent once done is closed, as nothing is reading any more.
+func readKeys(in io.Reader, keyPresses chan<- rune, moves chan<- rune, done <-chan struct{}) {
+	reader := bufio.NewReader(in)
+	send := func(to chan<- rune, key rune) bool {
+		select {
+		case to <- key:
+			return true
+		case <-done:
+			return false
+		}
+	}
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return
+		}
+		sent := true
+		switch b {
+		case 's', 'p', 'q', '[', ']':
+			sent = send(keyPresses, rune(b))
+		case 3: // ctrl-c, which doesn't send a signal in raw mode
+			sent = send(keyPresses, 'q')
+		case 'b':
+			sent = send(moves, toggleBraille)
+		case 0x1b: // arrow keys are sent as ESC [ A-D
+			if next, _ := reader.ReadByte(); next != '[' {
+				continue
+			}
+			arrow, _ := reader.ReadByte()
+			switch arrow {
+			case 'A':
+				sent = send(moves, moveUp)
+			case 'B':
+				sent = send(moves, moveDown)
+			case 'C':
+				sent = send(moves, moveRight)
+			case 'D':
+				sent = send(moves, moveLeft)
+			}
+		}
+		if !sent {
+			return
+		}
+	}
+}
+
+// flip records a CellFlipped event
+func (r *renderer) flip(cell util.Cell) {
+	i := cell.Y*r.width + cell.X
+	r.cells[i] = !r.cells[i]
+	if r.cells[i] {
+		r.alive++
+	} else {
+		r.alive--
+	}
+}
+
+// setState records a CellStateChanged event. Dying cells are drawn as dead, but cells of every colour are alive.
+func (r *renderer) setState(cell util.Cell, state uint8) {
+	alive := state == 255 || r.colours && state != 0
+	if alive != r.cells[cell.Y*r.width+cell.X] {
+		r.flip(cell)
+	}
+}
+
+// aliveAt checks whether a cell is alive, treating cells off the board as dead
+func (r *renderer) aliveAt(x, y int) bool {
+	return x < r.width && y < r.height && r.cells[y*r.width+x]
+}
+
+// viewport returns how many cells fit across and down the terminal, leaving the bottom row for the status line
+func (r *renderer) viewport() (int, int, int, int) {
+	columns, rows := r.size()
+	rows--
+	if r.braille {
+		return columns, rows, columns * 2, rows * 4
+	}
+	return columns, rows, columns, rows * 2
+}
+
+// move scrolls the viewport by a quarter of its size, or switches character set
+func (r *renderer) move(move rune) {
+	_, _, cellsWide, cellsHigh := r.viewport()
+	switch move {
+	case moveUp:
+		r.viewY -= cellsHigh / 4
+	case moveDown:
+		r.viewY += cellsHigh / 4
+	case moveLeft:
+		r.viewX -= cellsWide / 4
+	case moveRight:
+		r.viewX += cellsWide / 4
+	case toggleBraille:
+		r.braille = !r.braille
+	}
+}
+
+// clampView keeps the viewport on the board
+func (r *renderer) clampView(cellsWide, cellsHigh int) {
+	if r.viewX > r.width-cellsWide {
+		r.viewX = r.width - cellsWide
+	}
+	if r.viewY > r.height-cellsHigh {
+		r.viewY = r.height - cellsHigh
+	}
+	if r.viewX < 0 {
+		r.viewX = 0
+	}
+	if r.viewY < 0 {
+		r.viewY = 0
+	}
+}
+
+// draw writes the visible part of the board and the status line to the terminal
+func (r *renderer) draw() {
+	columns, rows, cellsWide, cellsHigh := r.viewport()
+	r.clampView(cellsWide, cellsHigh)
+	_, _ = r.out.WriteString(home)
+	for row := 0; row < rows; row++ {
+		for column := 0; column < columns; column++ {
+			_, _ = r.out.WriteRune(r.character(r.viewX, r.viewY, column, row))
+		}
+		_, _ = r.out.WriteString(clearLine + "\r\n")
+	}
+	status := fmt.Sprintf(" Turn %d | Alive %d | %v | View %d,%d of %dx%d | %v",
+		r.turn, r.alive, r.state, r.viewX, r.viewY, r.width, r.height, r.message)
+	if len(status) > columns {
+		status = status[:columns]
+	}
+	_, _ = r.out.WriteString(reverse + status + clearLine + reset)
+	util.Check(r.out.Flush())
+}
+
+// character returns the character for a terminal column and row, covering 1x2 cells in half-block mode or 2x4 in braille
+func (r *renderer) character(viewX, viewY, column, row int) rune {
+	if r.braille {
+		x, y := viewX+column*2, viewY+row*4
+		var dots rune
+		for i, bit := range [8]rune{0x01, 0x02, 0x04, 0x40, 0x08, 0x10, 0x20, 0x80} { // left column then right column, top to bottom
+			if r.aliveAt(x+i/4, y+i%4) {
+				dots |= bit
+			}
+		}
+		return 0x2800 + dots
+	}
+	x, y := viewX+column, viewY+row*2
+	top, bottom := r.aliveAt(x, y), r.aliveAt(x, y+1)
+	switch {
+	case top && bottom:
+		return '█'
+	case top:
+		return '▀'
+	case bottom:
+		return '▄'
+	default:
+		return ' '
+	}
+}
+
+// sizeFromEnv returns the terminal size given by $COLUMNS and $LINES, or 80x24 if they aren't set
+func sizeFromEnv() (int, int) {
+	columns, err := strconv.Atoi(os.Getenv("COLUMNS"))
+	if err != nil || columns <= 0 {
+		columns = 80
+	}
+	rows, err := strconv.Atoi(os.Getenv("LINES"))
+	if err != nil || rows <= 1 {
+		rows = 24
+	}
+	return columns, rows
+}
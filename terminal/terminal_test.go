@@ -0,0 +1,113 @@
+package terminal
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// screen returns what the renderer should write for the given rows and status line
+func screen(rows []string, status string) string {
+	drawn := home
+	for _, row := range rows {
+		drawn += row + clearLine + "\r\n"
+	}
+	return drawn + reverse + status + clearLine + reset
+}
+
+// TestDraw tests half-block and braille drawing of a glider, scrolled with the arrow keys, against golden screens
+func TestDraw(t *testing.T) {
+	var out bytes.Buffer
+	r := newRenderer(gol.Params{ImageWidth: 16, ImageHeight: 16}, &out, func() (int, int) { return 4, 3 })
+	for _, cell := range []util.Cell{{X: 1, Y: 0}, {X: 2, Y: 1}, {X: 0, Y: 2}, {X: 1, Y: 2}, {X: 2, Y: 2}} {
+		r.flip(cell)
+	}
+
+	moves := make(chan rune, 10)
+	keyPresses := make(chan rune, 10)
+	readKeys(strings.NewReader("\x1b[C\x1b[Bpb"), keyPresses, moves, make(chan struct{}))
+	close(moves)
+	if len(keyPresses) != 1 || <-keyPresses != 'p' {
+		t.Error("p should have been sent on to the game")
+	}
+
+	expected := []string{
+		screen([]string{" ▀▄ ", "▀▀▀ "}, " Tur"),
+		screen([]string{"▀▄  ", "▀▀  "}, " Tur"), // right by a quarter of the 4 cells across
+		screen([]string{"▄█  ", "    "}, " Tur"), // down by a quarter of the 4 cells down
+		screen([]string{"⠚⠀⠀⠀", "⠀⠀⠀⠀"}, " Tur"), // braille, with 2x4 cells per character from the same top left
+	}
+	r.draw()
+	for i := 0; ; i++ {
+		if given := out.String(); given != expected[i] {
+			t.Errorf("screen %d should be\n%q\nnot\n%q", i, expected[i], given)
+		}
+		move, ok := <-moves
+		if !ok {
+			break
+		}
+		out.Reset()
+		r.move(move)
+		r.draw()
+	}
+
+	out.Reset()
+	r = newRenderer(gol.Params{ImageWidth: 2, ImageHeight: 2}, &out, func() (int, int) { return 40, 2 })
+	r.flip(util.Cell{X: 1, Y: 1})
+	r.turn = 7
+	r.draw()
+	status := " Turn 7 | Alive 1 | Executing | View 0,0"
+	if expected := screen([]string{" ▄" + strings.Repeat(" ", 38)}, status); out.String() != expected {
+		t.Errorf("the screen should be\n%q\nnot\n%q", expected, out.String())
+	}
+}
+
+// TestStates tests that cells of every colour are drawn as alive for rules with colours, but dying cells are drawn as
+// dead for rules with dying states
+func TestStates(t *testing.T) {
+	for _, test := range []struct {
+		rule     string
+		row      string
+		expected int
+	}{
+		{"QuadLife", "██", 4},
+		{"B2/S/C4", "▀ ", 1},
+	} {
+		var out bytes.Buffer
+		r := newRenderer(gol.Params{ImageWidth: 2, ImageHeight: 2, Rule: test.rule}, &out, func() (int, int) { return 40, 2 })
+		for i, state := range []uint8{255, 191, 127, 63} {
+			r.setState(util.Cell{X: i % 2, Y: i / 2}, state)
+		}
+		r.draw()
+		status := fmt.Sprintf(" Turn 0 | Alive %d | Executing | View 0,0", test.expected)
+		if expected := screen([]string{test.row + strings.Repeat(" ", 38)}, status); out.String() != expected {
+			t.Errorf("%v: the screen should be\n%q\nnot\n%q", test.rule, expected, out.String())
+		}
+	}
+}
+
+// TestReadKeysStops tests that the key reader stops once the game is over instead of waiting to send a key
+func TestReadKeysStops(t *testing.T) {
+	in, keys := io.Pipe()
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		readKeys(in, make(chan rune), make(chan rune), done)
+		close(stopped)
+	}()
+	close(done)
+	go func() {
+		_, _ = keys.Write([]byte("q")) // nothing is reading key presses
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Error("the key reader should have stopped")
+	}
+}
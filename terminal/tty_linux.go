@@ -0,0 +1,67 @@
+//go:build linux
+// +build linux
+
+package terminal
+
+import (
+	"io"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// makeRaw stops the terminal from echoing and line buffering input, so single keys can be read straight away.
+// It returns a function that puts the terminal back how it was.
+func makeRaw(file *os.File) func() {
+	var old syscall.Termios
+	if ioctl(file, syscall.TCGETS, unsafe.Pointer(&old)) != nil { // not a terminal, e.g. input is piped in
+		return func() {}
+	}
+	raw := old
+	raw.Lflag &^= syscall.ECHO | syscall.ICANON | syscall.ISIG
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if ioctl(file, syscall.TCSETS, unsafe.Pointer(&raw)) != nil {
+		return func() {}
+	}
+	return func() {
+		_ = ioctl(file, syscall.TCSETS, unsafe.Pointer(&old))
+	}
+}
+
+// openKeys returns a copy of a file to read keys from that doesn't block, so closing it with the function it returns
+// stops a read that is waiting for a key. The file is put back to blocking once it is closed.
+func openKeys(file *os.File) (io.Reader, func()) {
+	fd, err := syscall.Dup(int(file.Fd()))
+	if err != nil {
+		return file, func() {}
+	}
+	if err := syscall.SetNonblock(fd, true); err != nil {
+		_ = syscall.Close(fd)
+		return file, func() {}
+	}
+	keys := os.NewFile(uintptr(fd), file.Name()) // polled by the runtime, as it doesn't block
+	return keys, func() {
+		_ = keys.Close()
+		_ = syscall.SetNonblock(int(file.Fd()), false) // the copy shared the blocking mode with the file
+	}
+}
+
+// size returns the number of columns and rows in the terminal
+func size(file *os.File) (int, int) {
+	var ws struct {
+		rows, columns, xPixels, yPixels uint16
+	}
+	if ioctl(file, syscall.TIOCGWINSZ, unsafe.Pointer(&ws)) != nil || ws.columns == 0 || ws.rows == 0 {
+		return sizeFromEnv()
+	}
+	return int(ws.columns), int(ws.rows)
+}
+
+func ioctl(file *os.File, request uintptr, arg unsafe.Pointer) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, file.Fd(), request, uintptr(arg))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+//go:build !linux
+// +build !linux
+
+package terminal
+
+import (
+	"io"
+	"os"
+)
+
+// makeRaw does nothing on platforms where we don't know how to change the terminal mode,
+// so keys are only read once enter is pressed.
+func makeRaw(file *os.File) func() {
+	return func() {}
+}
+
+// openKeys returns the file to read keys from. Reads can't be stopped part way through on these platforms, so the
+// key reader stops after the next key instead.
+func openKeys(file *os.File) (io.Reader, func()) {
+	return file, func() {}
+}
+
+// size returns the number of columns and rows in the terminal, as given by the environment
+func size(file *os.File) (int, int) {
+	return sizeFromEnv()
+}
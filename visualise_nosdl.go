@@ -0,0 +1,17 @@
+//go:build nosdl
+// +build nosdl
+
+package main
+
+import (
+	"fmt"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/terminal"
+)
+
+// runSdl falls back to the terminal renderer, as this binary was built with the nosdl tag
+func runSdl(p gol.Params, events <-chan gol.Event, keyPresses chan<- rune, commands chan<- gol.Command, patterns []gol.Pattern) {
+	fmt.Println("Built without SDL (-tags nosdl), drawing in the terminal instead")
+	terminal.Run(p, events, keyPresses)
+}
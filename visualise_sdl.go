@@ -0,0 +1,14 @@
+//go:build !nosdl
+// +build !nosdl
+
+package main
+
+import (
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/sdl"
+)
+
+// runSdl shows the game in an SDL window
+func runSdl(p gol.Params, events <-chan gol.Event, keyPresses chan<- rune, commands chan<- gol.Command, patterns []gol.Pattern) {
+	sdl.Run(p, events, keyPresses, commands, patterns)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestVolume tests 3D rules on the 32x32x16 image against the 26 neighbours of each cell counted here, with different
+// numbers of workers splitting the slices between them. The events are only for the slice being shown.
+func TestVolume(t *testing.T) {
+	const width, height, depth = 32, 32, 16
+	tests := []struct {
+		rule               string
+		survive, born      [2]int
+		expectedRulestring string
+	}{
+		{"4555", [2]int{4, 5}, [2]int{5, 5}, "4555"},
+		{"5,7,6,6", [2]int{5, 7}, [2]int{6, 6}, "5766"},
+	}
+	for _, test := range tests {
+		rule, err := gol.ParseRule(test.rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rule.String() != test.expectedRulestring {
+			t.Errorf("%v was read back as %v, not %v", test.rule, rule, test.expectedRulestring)
+		}
+		p := gol.Params{ImageWidth: width, ImageHeight: height, Depth: depth, Slice: 7, Turns: 10, Rule: test.rule}
+		alive := make(map[[3]int]bool)
+		for _, cell := range readAliveCells("images/32x32x16.pgm", width, height*depth) {
+			alive[[3]int{cell.X, cell.Y % height, cell.Y / height}] = true
+		}
+		for turn := 0; turn < p.Turns; turn++ {
+			next := make(map[[3]int]bool)
+			for z := 0; z < depth; z++ {
+				for y := 0; y < height; y++ {
+					for x := 0; x < width; x++ {
+						count := 0
+						for dz := -1; dz <= 1; dz++ {
+							for dy := -1; dy <= 1; dy++ {
+								for dx := -1; dx <= 1; dx++ {
+									if (dx != 0 || dy != 0 || dz != 0) && alive[[3]int{(x + dx + width) % width, (y + dy + height) % height, (z + dz + depth) % depth}] {
+										count++
+									}
+								}
+							}
+						}
+						limits := test.born
+						if alive[[3]int{x, y, z}] {
+							limits = test.survive
+						}
+						if count >= limits[0] && count <= limits[1] {
+							next[[3]int{x, y, z}] = true
+						}
+					}
+				}
+			}
+			alive = next
+		}
+		var expected, expectedSlice []util.Cell
+		for cell := range alive {
+			expected = append(expected, util.Cell{X: cell[0], Y: cell[2]*height + cell[1]})
+			if cell[2] == p.Slice {
+				expectedSlice = append(expectedSlice, util.Cell{X: cell[0], Y: cell[1]})
+			}
+		}
+
+		for _, threads := range []int{1, 3, 8, 20} {
+			p.Threads = threads
+			t.Run(fmt.Sprintf("%v-%d", test.rule, threads), func(t *testing.T) {
+				events := make(chan gol.Event)
+				go gol.Run(p, events, nil)
+				flipped := make(map[util.Cell]bool)
+				for event := range events {
+					switch e := event.(type) {
+					case gol.CellFlipped:
+						flipped[e.Cell] = !flipped[e.Cell]
+					case gol.FinalTurnComplete:
+						assertEqualBoard(t, e.Alive, expectedSlice, p)
+					}
+				}
+				var slice []util.Cell
+				for cell, isAlive := range flipped {
+					if isAlive {
+						slice = append(slice, cell)
+					}
+				}
+				assertEqualBoard(t, slice, expectedSlice, p)
+				output := readAliveCells(fmt.Sprintf("out/32x32x16x%d.pgm", p.Turns), width, height*depth)
+				assertEqualBoard(t, output, expected, gol.Params{ImageWidth: width, ImageHeight: height * depth})
+			})
+		}
+
+		t.Run(test.rule+"-ShowSlice", func(t *testing.T) { // starting from another slice and switching before the first turn
+			shown := p
+			shown.Slice = 2
+			commands := make(chan gol.Command, 1)
+			commands <- gol.ShowSlice{Z: p.Slice - depth}
+			events := make(chan gol.Event)
+			go gol.RunWithCommands(shown, events, nil, commands)
+			flipped := make(map[util.Cell]bool)
+			for event := range events {
+				if e, ok := event.(gol.CellFlipped); ok {
+					flipped[e.Cell] = !flipped[e.Cell]
+				}
+			}
+			var slice []util.Cell
+			for cell, isAlive := range flipped {
+				if isAlive {
+					slice = append(slice, cell)
+				}
+			}
+			assertEqualBoard(t, slice, expectedSlice, p)
+		})
+	}
+
+	for _, size := range [][3]int{{32, 32, 1}, {32, 32, 2}, {2, 32, 16}, {32, 2, 16}} { // some neighbours would be the same cell
+		small := gol.Params{ImageWidth: size[0], ImageHeight: size[1], Depth: size[2], Rule: "4555"}
+		if err := gol.CheckParams(small); err == nil {
+			t.Errorf("a %dx%dx%d board should be too small for a 3D rule", size[0], size[1], size[2])
+		}
+	}
+	if err := gol.CheckParams(gol.Params{ImageWidth: 3, ImageHeight: 3, Depth: 3, Rule: "4555"}); err != nil {
+		t.Errorf("a 3x3x3 board should be big enough for a 3D rule, but got %v", err)
+	}
+
+	for _, rulestring := range []string{"455", "4,5,5", "5455", "4,5,5,27"} {
+		if _, err := gol.ParseRule(rulestring); err == nil {
+			t.Errorf("%v should not be a valid 3D rule", rulestring)
+		}
+	}
+}